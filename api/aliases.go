@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"net/http"
+)
+
+// shortened: The primary shortened URL of the link
+// alias: The alias slug to add or remove
+type LinkAliasRequest struct {
+	Shortened string `json:"shortened"`
+	Alias     string `json:"alias"`
+}
+
+// AddLinkAliasHandler adds an alias slug to a link.
+// @Summary Add an alias to a link
+// @Description Adds an extra slug that redirects to the same link and shares its analytics.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body LinkAliasRequest true "Alias request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/add-alias [post]
+func AddLinkAliasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request LinkAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	if !isAlphanumeric(request.Alias) {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Alias must be alphanumeric",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	db := database.GetDB()
+
+	exists, err := isShortenedURLTaken(db, models.LinkSlugScope(*link), request.Alias)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to validate alias",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if exists {
+		config := ErrorResponseConfig{
+			Status:    http.StatusConflict,
+			Message:   "Alias is already in use, or is reserved",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	alias, err := models.AddLinkAlias(db, link.ID, models.LinkSlugScope(*link), request.Alias, ctxValues.KeyID)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to add alias",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	link.Aliases = append(link.Aliases, *alias)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionAliasAdd, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, nil, map[string]interface{}{"alias": request.Alias})
+}
+
+// RemoveLinkAliasHandler removes an alias slug from a link.
+// @Summary Remove an alias from a link
+// @Description Removes an alias slug from a link. The slug stops redirecting and becomes available again.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body LinkAliasRequest true "Alias request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/remove-alias [post]
+func RemoveLinkAliasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request LinkAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	removed, err := models.RemoveLinkAlias(database.GetDB(), link.ID, request.Alias)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to remove alias",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if !removed {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Alias not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Shortened: %s, Alias: %s", request.Shortened, request.Alias),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	remaining := make([]models.LinkAlias, 0, len(link.Aliases))
+	for _, alias := range link.Aliases {
+		if alias.Slug != request.Alias {
+			remaining = append(remaining, alias)
+		}
+	}
+	link.Aliases = remaining
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionAliasRemove, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, map[string]interface{}{"alias": request.Alias}, nil)
+}
@@ -2,12 +2,8 @@ package api
 
 import (
 	"encoding/json"
-	"go-link-shortener/database"
 	"go-link-shortener/lib"
-	"go-link-shortener/models"
-	"go-link-shortener/utils"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/chi"
 )
@@ -51,18 +47,57 @@ func V1Router() chi.Router {
 
 		r.Route(lib.ROUTES.Links.Base, func(r chi.Router) {
 			r.Post(lib.ROUTES.Links.Shorten, ShortenHandler)
+			r.Post(lib.ROUTES.Links.BulkShorten, BulkShortenHandler)
 			r.Post(lib.ROUTES.Links.Retrieve, RetrieveLinkHandler)
 			// validates self link
 			r.Post(lib.ROUTES.Links.Delete, DeleteLinkHandler)
 			// validates self link
 			r.Post(lib.ROUTES.Links.Update, UpdateLinkHandler)
+			// validates self links
+			r.Post(lib.ROUTES.Links.BulkUpdate, BulkUpdateLinksHandler)
+			// validates self links
+			r.Post(lib.ROUTES.Links.BulkDelete, BulkDeleteLinksHandler)
 			// validates self link
 			r.Post(lib.ROUTES.Links.RetrieveAllByKey, RetrieveAllLinksByKeyHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.Trash, RetrieveTrashedLinksHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.Restore, RestoreLinkHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.Purge, PurgeLinkHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.Revisions, RetrieveLinkRevisionsHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.Rollback, RollbackLinkHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.HistoricalSlugs, RetrieveHistoricalSlugsHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.ReleaseHistoricalSlug, ReleaseHistoricalSlugHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.AddAlias, AddLinkAliasHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.RemoveAlias, RemoveLinkAliasHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.SetRules, SetLinkRulesHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.AddVariant, AddLinkVariantHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.UpdateVariant, UpdateLinkVariantHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.RemoveVariant, RemoveLinkVariantHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.ReweightVariants, ReweightLinkVariantsHandler)
+			// validates self link
+			r.Post(lib.ROUTES.Links.VariantStats, RetrieveVariantStatsHandler)
+			r.Post(lib.ROUTES.Links.TagStats, RetrieveTagStatsHandler)
+			r.Post(lib.ROUTES.Links.Search, SearchLinksHandler)
+			r.Get(lib.ROUTES.Links.QR, LinkQRCodeHandler)
 
 			r.Group(func(r chi.Router) {
 				// Use AdminOnlyMiddleware for admin only routes
 				r.Use(AdminOnlyMiddleware)
 				r.Get(lib.ROUTES.Links.RetrieveAll, RetrieveAllLinksHandler)
+				r.Post(lib.ROUTES.Links.Import, ImportLinksHandler)
 			})
 		})
 
@@ -79,62 +114,28 @@ func V1Router() chi.Router {
 			})
 		})
 
-	})
-
-	return r
-}
-
-func RedirectRouter() chi.Router {
-	r := chi.NewRouter()
-
-	env := utils.LoadEnv()
-
-	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
-		// blank path, redirect to docs
-		if r.URL.Path == "/" {
-			if env.ENABLE_DOCS == "false" {
-				http.Redirect(w, r, lib.ROUTES.NotFound, http.StatusFound)
-				return
-			}
-			http.Redirect(w, r, lib.ROUTES.Docs+"/", http.StatusFound)
-			return
-		}
-
-		db := database.GetDB()
+		r.Route(lib.ROUTES.Domains.Base, func(r chi.Router) {
+			r.Use(AdminOnlyMiddleware)
+			r.Get(lib.ROUTES.Domains.RetrieveAll, RetrieveAllDomainsHandler)
+			r.Post(lib.ROUTES.Domains.Create, CreateDomainHandler)
+			r.Post(lib.ROUTES.Domains.Update, UpdateDomainHandler)
+			r.Post(lib.ROUTES.Domains.Delete, DeleteDomainHandler)
+		})
 
-		// remove leading slash
-		fixedPath := r.URL.Path[1:]
-		linkObj, err := RetrieveRedirectURL(db, fixedPath)
+		r.Route(lib.ROUTES.Namespaces.Base, func(r chi.Router) {
+			r.Get(lib.ROUTES.Namespaces.RetrieveAll, RetrieveNamespacesHandler)
+			r.Post(lib.ROUTES.Namespaces.Create, CreateNamespaceHandler)
+			// validates namespace owner
+			r.Post(lib.ROUTES.Namespaces.Update, UpdateNamespaceHandler)
+			// validates namespace owner
+			r.Post(lib.ROUTES.Namespaces.Delete, DeleteNamespaceHandler)
+		})
 
-		if err != nil {
-			// check if err is "record not found"
-			if err.Error() == "record not found" {
-				http.Error(w, "Link with shortened string '"+fixedPath+"' not found", http.StatusNotFound)
-				return
-			}
-			// if not, return internal server error
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-
-		// update the last visited time, increment visits, and add a new record to the link_visits table
-		now := time.Now()
-		linkObj.Visits += 1
-		linkObj.LastVisitedAt = &now
-		db.Save(linkObj)
-
-		userAgent := r.Header.Get("User-Agent")
-		ipAddress := r.RemoteAddr
-		referrer := r.Header.Get("Referer")
-
-		db.Model(&linkObj).Association("Visits").Append(&models.LinkVisit{
-			VisitedAt: now,
-			UserAgent: &userAgent,
-			IPAddress: &ipAddress,
-			Referrer:  &referrer,
+		r.Route(lib.ROUTES.Audit.Base, func(r chi.Router) {
+			r.Use(AdminOnlyMiddleware)
+			r.Post(lib.ROUTES.Audit.Retrieve, RetrieveAuditEventsHandler)
 		})
 
-		http.Redirect(w, r, linkObj.RedirectTo, http.StatusMovedPermanently)
 	})
 
 	return r
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recordAuditEvent stores an audit event for a mutation performed by the requesting key
+func recordAuditEvent(r *http.Request, ctxValues ContextValues, action models.AuditAction, targetType models.AuditTargetType,
+	targetID string, targetName string, before, after map[string]interface{}) {
+	sourceIP := GetClientIP(r)
+
+	models.CreateAuditEvent(models.AuditEvent{
+		ActorKeyID: ctxValues.KeyID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		SourceIP:   &sourceIP,
+	}, before, after)
+}
+
+// actor_key_id: Only return events performed by this key ID
+// target: Only return events for this target ID, link shortened URL or key name
+// from/to: Only return events within this time range
+// sort: timestamp (default)
+type RetrieveAuditEventsRequest struct {
+	ActorKeyID *uuid.UUID `json:"actor_key_id,omitempty"`
+	Target     string     `json:"target,omitempty"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+	ListRequest
+}
+
+type AuditEventResponse struct {
+	ID         uuid.UUID       `json:"id"`
+	ActorKeyID uuid.UUID       `json:"actor_key_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	TargetName string          `json:"target_name"`
+	Timestamp  time.Time       `json:"timestamp"`
+	SourceIP   *string         `json:"source_ip,omitempty"`
+	Diff       json.RawMessage `json:"diff" swaggertype:"object"`
+}
+
+type RetrieveAuditEventsResponse struct {
+	Message string               `json:"message"`
+	Events  []AuditEventResponse `json:"events"`
+	models.PageInfo
+}
+
+// convert models.AuditEvent to AuditEventResponse
+func ToAuditEventResponse(e models.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		ID:         e.ID,
+		ActorKeyID: e.ActorKeyID,
+		Action:     string(e.Action),
+		TargetType: string(e.TargetType),
+		TargetID:   e.TargetID,
+		TargetName: e.TargetName,
+		Timestamp:  e.Timestamp,
+		SourceIP:   e.SourceIP,
+		Diff:       json.RawMessage(e.Diff),
+	}
+}
+
+// RetrieveAuditEventsHandler retrieves audit events. Requires admin permissions.
+// @Summary Retrieve audit events
+// @Description Retrieves a page of the audit trail of key and link mutations, newest first, filtered by actor, target or time range.
+// @Tags audit,admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RetrieveAuditEventsRequest true "Audit event retrieval request"
+// @Success 200 {object} RetrieveAuditEventsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/audit/retrieve [post]
+func RetrieveAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		config := ErrorResponseConfig{
+			Status:    http.StatusMethodNotAllowed,
+			Message:   "Method not allowed",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAudit,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request RetrieveAuditEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAudit,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	db := database.GetDB()
+	if db == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   lib.ERRORS.Database,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAudit,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	events, page, err := models.RetrieveAuditEvents(db, models.AuditEventFilter{
+		ActorKeyID: request.ActorKeyID,
+		Target:     request.Target,
+		From:       request.From,
+		To:         request.To,
+	}, request.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAudit,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  err.Error(),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	responseEvents := make([]AuditEventResponse, 0, len(events))
+	for _, event := range events {
+		responseEvents = append(responseEvents, ToAuditEventResponse(event))
+	}
+
+	response := RetrieveAuditEventsResponse{
+		Message:  "Audit events retrieved successfully",
+		Events:   responseEvents,
+		PageInfo: page,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Server Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAudit,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceAudit,
+		"Retrieved audit events. Requested by: '"+ctxValues.SecretKey+"'", r.RemoteAddr)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"go-link-shortener/auth"
 	"go-link-shortener/database"
 	"go-link-shortener/lib"
@@ -9,6 +10,8 @@ import (
 	"go-link-shortener/utils"
 	"log"
 	"net/http"
+
+	"github.com/google/uuid"
 )
 
 type ValidateKeyResponse struct {
@@ -18,6 +21,7 @@ type ValidateKeyResponse struct {
 
 type ContextValues struct {
 	SecretKey string
+	KeyID     uuid.UUID
 	IsAdmin   bool
 }
 
@@ -73,12 +77,14 @@ func ValidateKeyHandler(w http.ResponseWriter, r *http.Request) {
 	response := ValidateKeyResponse{
 		Message: "Key validated successfully",
 		Key: StrippedKey{
-			Key:       keyObj.Key,
-			Name:      keyObj.Name,
-			CreatedAt: utils.SafeString(&keyObj.CreatedAt),
-			UpdatedAt: utils.SafeString(&keyObj.UpdatedAt),
-			IsActive:  keyObj.IsActive,
-			IsAdmin:   keyObj.IsAdmin,
+			Key:         keyObj.Key,
+			Name:        keyObj.Name,
+			CreatedAt:   utils.SafeString(&keyObj.CreatedAt),
+			UpdatedAt:   utils.SafeString(&keyObj.UpdatedAt),
+			IsActive:    keyObj.IsActive,
+			IsAdmin:     keyObj.IsAdmin,
+			FallbackURL: keyObj.FallbackURL,
+			DisplayName: keyObj.DisplayName,
 		},
 	}
 
@@ -180,12 +186,14 @@ func GenerateKeyHandler(w http.ResponseWriter, r *http.Request) {
 	response := GenerateKeyResponse{
 		Message: "Key generated successfully",
 		Key: StrippedKey{
-			Key:       newKeyObj.Key,
-			Name:      newKeyObj.Name,
-			CreatedAt: utils.SafeString(&newKeyObj.CreatedAt),
-			UpdatedAt: utils.SafeString(&newKeyObj.UpdatedAt),
-			IsActive:  newKeyObj.IsActive,
-			IsAdmin:   newKeyObj.IsAdmin,
+			Key:         newKeyObj.Key,
+			Name:        newKeyObj.Name,
+			CreatedAt:   utils.SafeString(&newKeyObj.CreatedAt),
+			UpdatedAt:   utils.SafeString(&newKeyObj.UpdatedAt),
+			IsActive:    newKeyObj.IsActive,
+			IsAdmin:     newKeyObj.IsAdmin,
+			FallbackURL: newKeyObj.FallbackURL,
+			DisplayName: newKeyObj.DisplayName,
 		},
 	}
 
@@ -210,6 +218,8 @@ func GenerateKeyHandler(w http.ResponseWriter, r *http.Request) {
 
 	models.CreateLog(models.LogTypeInfo, models.LogSourceAuth,
 		"Generated a new key with name: '"+request.Name+"'. Requested by: '"+ctxValues.SecretKey+"'", r.RemoteAddr)
+	recordAuditEvent(r, ctxValues, models.AuditActionKeyGenerate, models.AuditTargetKey,
+		newKeyObj.ID.String(), newKeyObj.Name, nil, models.KeyAuditSnapshot(*newKeyObj))
 }
 
 type DeleteKeyRequest struct {
@@ -271,6 +281,12 @@ func DeleteKeyHandler(w http.ResponseWriter, r *http.Request) {
 	ctxValues, _ := GetContextValues(r)
 	log.Println("Delete Key Request:'" + request.Key + "'. Requested by: '" + ctxValues.SecretKey + "'")
 
+	// snapshot the key before deletion for the audit trail
+	var deletedKeyObj *models.SecretKey
+	if db := database.GetDB(); db != nil {
+		deletedKeyObj = models.SearchKeyByKey(db, request.Key)
+	}
+
 	message, err := auth.DeleteKeyByKey(request.Key)
 	if err != nil {
 		config := ErrorResponseConfig{
@@ -303,13 +319,21 @@ func DeleteKeyHandler(w http.ResponseWriter, r *http.Request) {
 
 	models.CreateLog(models.LogTypeInfo, models.LogSourceAuth,
 		"Deleted key: '"+ctxValues.SecretKey+"'. Requested by: '"+ctxValues.SecretKey+"'", r.RemoteAddr)
+	if deletedKeyObj != nil {
+		recordAuditEvent(r, ctxValues, models.AuditActionKeyDelete, models.AuditTargetKey,
+			deletedKeyObj.ID.String(), deletedKeyObj.Name, models.KeyAuditSnapshot(*deletedKeyObj), nil)
+	}
 }
 
+// fallback_url: The URL visitors of the key's expired or deactivated links are sent to, unless the link sets its own. An empty string removes it
+// display_name: The owner name shown publicly on the preview page of the key's links. An empty string hides it
 type UpdateKeyRequest struct {
-	Key      string `json:"key"`
-	Name     string `json:"name"`
-	IsAdmin  *bool  `json:"is_admin"`
-	IsActive *bool  `json:"is_active"`
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	IsAdmin     *bool   `json:"is_admin"`
+	IsActive    *bool   `json:"is_active"`
+	FallbackURL *string `json:"fallback_url,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
 }
 
 type UpdateKeyResponse struct {
@@ -318,12 +342,14 @@ type UpdateKeyResponse struct {
 }
 
 type StrippedKey struct {
-	Key       string `json:"key"`
-	Name      string `json:"name"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	IsActive  bool   `json:"is_active"`
-	IsAdmin   bool   `json:"is_admin"`
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+	IsActive    bool    `json:"is_active"`
+	IsAdmin     bool    `json:"is_admin"`
+	FallbackURL *string `json:"fallback_url"`
+	DisplayName *string `json:"display_name"`
 }
 
 func buildUpdateRequest(req UpdateKeyRequest) auth.UpdateKeyS {
@@ -341,6 +367,12 @@ func buildUpdateRequest(req UpdateKeyRequest) auth.UpdateKeyS {
 	if req.IsActive != nil {
 		updateReq.IsAdmin = req.IsAdmin
 	}
+	if req.FallbackURL != nil {
+		updateReq.FallbackURL = req.FallbackURL
+	}
+	if req.DisplayName != nil {
+		updateReq.DisplayName = req.DisplayName
+	}
 
 	return updateReq
 }
@@ -396,8 +428,33 @@ func UpdateKeyHandler(w http.ResponseWriter, r *http.Request) {
 	ctxValues, _ := GetContextValues(r)
 	log.Println("Update Key Request:'"+request.Key+"', Name:'"+request.Name+"', IsAdmin:", request.IsAdmin, ", IsActive:", request.IsActive, ". Requested by: '"+ctxValues.SecretKey+"'")
 
+	if request.FallbackURL != nil && *request.FallbackURL != "" {
+		normalized, err := validateAndNormalizeURL(*request.FallbackURL)
+		if err != nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   fmt.Sprintf("invalid fallback_url: %v", err),
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceAuth,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		request.FallbackURL = &normalized
+	}
+
 	updateRequest := buildUpdateRequest(request)
 
+	// snapshot the key before the update for the audit trail
+	var keyBefore map[string]interface{}
+	if db := database.GetDB(); db != nil {
+		if existingKeyObj := models.SearchKeyByKey(db, request.Key); existingKeyObj != nil {
+			keyBefore = models.KeyAuditSnapshot(*existingKeyObj)
+		}
+	}
+
 	message, updatedKeyObj, err := auth.UpdateKey(updateRequest)
 	if err != nil {
 		config := ErrorResponseConfig{
@@ -410,7 +467,7 @@ func UpdateKeyHandler(w http.ResponseWriter, r *http.Request) {
 			Addendum:  "Requested by: '" + ctxValues.SecretKey + "'",
 		}
 
-		if err.Error() == lib.ERRORS.NoNewFields {
+		if err.Error() == lib.ERRORS.NoNewFields || err.Error() == lib.ERRORS.DisplayNameTooLong {
 			config.Status = http.StatusBadRequest
 		}
 
@@ -424,12 +481,14 @@ func UpdateKeyHandler(w http.ResponseWriter, r *http.Request) {
 	response := UpdateKeyResponse{
 		Message: message,
 		Key: &StrippedKey{
-			Key:       updatedKeyObj.Key,
-			Name:      updatedKeyObj.Name,
-			CreatedAt: utils.SafeString(&updatedKeyObj.CreatedAt),
-			UpdatedAt: utils.SafeString(&updatedKeyObj.UpdatedAt),
-			IsActive:  updatedKeyObj.IsActive,
-			IsAdmin:   updatedKeyObj.IsAdmin,
+			Key:         updatedKeyObj.Key,
+			Name:        updatedKeyObj.Name,
+			CreatedAt:   utils.SafeString(&updatedKeyObj.CreatedAt),
+			UpdatedAt:   utils.SafeString(&updatedKeyObj.UpdatedAt),
+			IsActive:    updatedKeyObj.IsActive,
+			IsAdmin:     updatedKeyObj.IsAdmin,
+			FallbackURL: updatedKeyObj.FallbackURL,
+			DisplayName: updatedKeyObj.DisplayName,
 		},
 	}
 
@@ -448,21 +507,32 @@ func UpdateKeyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	models.CreateLog(models.LogTypeInfo, models.LogSourceAuth, "Updated key: '"+request.Key+"'. Requested by: '"+ctxValues.SecretKey+"'", r.RemoteAddr)
+	recordAuditEvent(r, ctxValues, models.AuditActionKeyUpdate, models.AuditTargetKey,
+		updatedKeyObj.ID.String(), updatedKeyObj.Name, keyBefore, models.KeyAuditSnapshot(*updatedKeyObj))
 }
 
 type RetrieveAllKeysResponse struct {
 	Message string        `json:"message"`
 	Keys    []StrippedKey `json:"keys"`
+	models.PageInfo
 }
 
 // RetrieveAllKeysHandler retrieves all secret keys. Requires admin permissions.
 
 // @Summary Retrieve all secret keys
-// @Description Retrieves all secret keys from the database.
+// @Description Retrieves a page of the secret keys from the database, newest first by default. Pass next_cursor as cursor to fetch the next page.
 // @Tags auth,admin
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param cursor query string false "The next_cursor of the previous page"
+// @Param sort query string false "created_at (default) or name"
+// @Param order query string false "asc or desc, the default is desc for created_at and asc for name"
+// @Param is_active query bool false "Only retrieve active or inactive keys"
+// @Param is_admin query bool false "Only retrieve admin or non-admin keys"
+// @Param created_from query string false "Only retrieve keys created at or after this RFC 3339 time"
+// @Param created_to query string false "Only retrieve keys created at or before this RFC 3339 time"
 // @Success 200 {object} RetrieveAllKeysResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
@@ -507,24 +577,69 @@ func RetrieveAllKeysHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// retrieve all keys from the database
-	keys := models.RetrieveAllKeys(db)
+	query := r.URL.Query()
+	listRequest, err := listRequestFromQuery(query)
+	var filter models.KeyFilter
+	if err == nil {
+		filter.IsActive, err = parseBoolQuery(query, "is_active")
+	}
+	if err == nil {
+		filter.IsAdmin, err = parseBoolQuery(query, "is_admin")
+	}
+	if err == nil {
+		filter.CreatedFrom, err = parseTimeQuery(query, "created_from")
+	}
+	if err == nil {
+		filter.CreatedTo, err = parseTimeQuery(query, "created_to")
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAuth,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	// retrieve a page of keys from the database
+	keys, page, err := models.RetrieveAllKeys(db, filter, listRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceAuth,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
 
 	strippedKeys := make([]StrippedKey, 0, len(keys))
 	for _, key := range keys {
 		strippedKeys = append(strippedKeys, StrippedKey{
-			Key:       key.Key,
-			Name:      key.Name,
-			CreatedAt: utils.SafeString(&key.CreatedAt),
-			UpdatedAt: utils.SafeString(&key.UpdatedAt),
-			IsActive:  key.IsActive,
-			IsAdmin:   key.IsAdmin,
+			Key:         key.Key,
+			Name:        key.Name,
+			CreatedAt:   utils.SafeString(&key.CreatedAt),
+			UpdatedAt:   utils.SafeString(&key.UpdatedAt),
+			IsActive:    key.IsActive,
+			IsAdmin:     key.IsAdmin,
+			FallbackURL: key.FallbackURL,
+			DisplayName: key.DisplayName,
 		})
 	}
 
 	response := RetrieveAllKeysResponse{
-		Message: "Keys retrieved successfully",
-		Keys:    strippedKeys,
+		Message:  "Keys retrieved successfully",
+		Keys:     strippedKeys,
+		PageInfo: page,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,727 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// links: The links to create, each one is a regular shorten request
+// atomic: Create every link or none of them in one transaction. If false, the valid links are created and the others report their error
+type BulkShortenRequest struct {
+	Links  []ShortenRequest `json:"links"`
+	Atomic bool             `json:"atomic"`
+}
+
+// index: The position of the link in the request
+// link: The created link, nil if it wasn't created
+// error: Why the link wasn't created
+type BulkShortenResult struct {
+	Index   int              `json:"index"`
+	Success bool             `json:"success"`
+	Link    *ShortenResponse `json:"link,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// results: One result per requested link, in the order of the request
+type BulkShortenResponse struct {
+	Message string              `json:"message"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Results []BulkShortenResult `json:"results"`
+}
+
+// errBatchFailed rolls back an atomic batch once one of its links failed
+var errBatchFailed = errors.New("a link of the batch failed")
+
+// batchSlugKey identifies the slug a shorten request claims, so that two requests for the same slug in one batch can be told apart
+func batchSlugKey(req ShortenRequest) string {
+	return models.NormalizeHost(req.Domain) + "/" + strings.TrimSpace(req.Namespace) + "/" + req.CustomURL
+}
+
+// findBatchSlugCollisions returns the index of every request whose custom_url was already claimed by an earlier request
+// of the batch on the same domain and namespace, mapped to the index of that earlier request
+func findBatchSlugCollisions(requests []ShortenRequest) map[int]int {
+	claimed := make(map[string]int, len(requests))
+	collisions := make(map[int]int)
+	for i, req := range requests {
+		if req.CustomURL == "" {
+			continue
+		}
+		key := batchSlugKey(req)
+		if first, ok := claimed[key]; ok {
+			collisions[i] = first
+			continue
+		}
+		claimed[key] = i
+	}
+	return collisions
+}
+
+// createBatchLink creates one link of a batch, checking the key's access to its domain and namespace first.
+// The returned path locates the created link.
+func createBatchLink(db *gorm.DB, req ShortenRequest, keyID uuid.UUID, isAdmin bool) (*ShortenResponse, linkPath, error) {
+	domain, namespace, _, err := resolveShortenScope(db, req, keyID, isAdmin)
+	if err != nil {
+		return nil, linkPath{}, err
+	}
+	res, err := CreateLink(db, req, keyID, domain, namespace)
+	if err != nil {
+		return nil, linkPath{}, err
+	}
+	return res, linkPath{Domain: domain, Namespace: namespace, Slug: res.ShortenedURL}, nil
+}
+
+// BulkShortenHandler shortens many URLs in one request.
+// @Summary Shorten many URLs
+// @Description Creates up to BULK_SHORTEN_LIMIT links (default 500) and reports the result of each one in the order of the request.
+// @Description Custom URLs repeated within the batch fail, the first request for the slug wins.
+// @Description With atomic, either every link is created or none is: if one fails, the others report that they were rolled back and the status is 400.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body BulkShortenRequest true "Bulk URL shortening request"
+// @Success 200 {object} BulkShortenResponse
+// @Failure 400 {object} BulkShortenResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/bulk-shorten [post]
+func BulkShortenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request BulkShortenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	limit := utils.ENV.BULK_SHORTEN_LIMIT
+	if len(request.Links) == 0 || len(request.Links) > limit {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   fmt.Sprintf("links must contain between 1 and %d links", limit),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   lib.ERRORS.Database,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	results := make([]BulkShortenResult, len(request.Links))
+	paths := make([]linkPath, len(request.Links))
+	collisions := findBatchSlugCollisions(request.Links)
+
+	// links with a custom_url are created first, so that a generated slug can't take the custom_url of a later link
+	order := make([]int, 0, len(request.Links))
+	for _, custom := range []bool{true, false} {
+		for i, req := range request.Links {
+			if (req.CustomURL != "") == custom {
+				order = append(order, i)
+			}
+		}
+	}
+
+	// createAll creates the links one after another, each in its own (nested) transaction
+	createAll := func(tx *gorm.DB) {
+		for _, i := range order {
+			req := request.Links[i]
+			results[i].Index = i
+			if first, ok := collisions[i]; ok {
+				results[i].Error = fmt.Sprintf("custom_url is already used by link %d of the batch", first)
+				continue
+			}
+			res, path, err := createBatchLink(tx, req, ctxValues.KeyID, ctxValues.IsAdmin)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Success = true
+			results[i].Link = res
+			paths[i] = path
+		}
+	}
+
+	countFailed := func() int {
+		count := 0
+		for _, result := range results {
+			if !result.Success {
+				count++
+			}
+		}
+		return count
+	}
+
+	status := http.StatusOK
+	message := "Links created successfully"
+	if request.Atomic {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			createAll(tx)
+			if countFailed() > 0 {
+				return errBatchFailed
+			}
+			return nil
+		})
+		if err != nil {
+			// nothing was created, the links that were valid report the rollback
+			for i := range results {
+				if results[i].Success {
+					results[i].Success = false
+					results[i].Link = nil
+					results[i].Error = "rolled back because another link of the batch failed"
+				}
+			}
+			if !errors.Is(err, errBatchFailed) {
+				config := ErrorResponseConfig{
+					Status:    http.StatusInternalServerError,
+					Message:   "Failed to create links",
+					LogType:   models.LogTypeError,
+					LogSource: models.LogSourceLinks,
+					Request:   r,
+					CtxValues: &ctxValues,
+					Addendum:  fmt.Sprintf("Error: %v", err),
+				}
+				writeErrorResponse(w, config)
+				return
+			}
+			status = http.StatusBadRequest
+			message = "No links were created because some of them failed"
+		}
+	} else {
+		createAll(db)
+	}
+
+	failed := countFailed()
+	if !request.Atomic && failed > 0 {
+		message = "Some links could not be created"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(BulkShortenResponse{
+		Message: message,
+		Created: len(results) - failed,
+		Failed:  failed,
+		Results: results,
+	})
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceLinks,
+		fmt.Sprintf("Bulk created %d of %d links. Requested by: '%s'", len(results)-failed, len(results), ctxValues.SecretKey), r.RemoteAddr)
+
+	for i, result := range results {
+		if !result.Success {
+			continue
+		}
+		if link, err := retrieveScopedLink(db, paths[i].Scope(), paths[i].Slug); err == nil {
+			recordAuditEvent(r, ctxValues, models.AuditActionLinkCreate, models.AuditTargetLink,
+				link.ID.String(), link.Shortened, nil, models.LinkAuditSnapshot(*link))
+		}
+	}
+}
+
+// shortened: The links to change, referred to as in /v1/links/retrieve, e.g. "docs" or "go.example.com/eng/docs"
+// filter: Selects the links to change instead of shortened, it must set at least one criterion
+// dry_run: Only report which links would change, without changing them
+type BulkLinkSelection struct {
+	Shortened []string               `json:"shortened,omitempty"`
+	Filter    *BulkLinkFilterRequest `json:"filter,omitempty"`
+	DryRun    bool                   `json:"dry_run"`
+}
+
+// key: Only select the links created by this secret key, non-admin keys may only use their own
+// created_to: Only select the links created before this time, e.g. the end of a campaign
+type BulkLinkFilterRequest struct {
+	Key string `json:"key,omitempty"`
+	LinkFilterRequest
+}
+
+// is_active: Activates or deactivates the links
+// expires_at: Sets the expiry of the links, 1970-01-01T00:00:00Z removes it
+// destination_domain: Replaces the host of the links' redirect_to, keeping its scheme, path and query, e.g. "new.example.com".
+// The port of redirect_to is kept unless one is given, e.g. "new.example.com:8443"
+type BulkLinkPatch struct {
+	IsActive          *bool      `json:"is_active,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	DestinationDomain string     `json:"destination_domain,omitempty"`
+}
+
+type BulkUpdateLinksRequest struct {
+	BulkLinkSelection
+	Patch BulkLinkPatch `json:"patch"`
+}
+
+type BulkDeleteLinksRequest struct {
+	BulkLinkSelection
+}
+
+// shortened: The link as it was selected, or as other endpoints refer to it if it was selected by filter
+// changes: The fields that changed, or would change in a dry run
+// error: Why the link wasn't changed
+type BulkLinkResult struct {
+	Shortened string                             `json:"shortened"`
+	Success   bool                               `json:"success"`
+	Changes   map[string]models.AuditFieldChange `json:"changes,omitempty"`
+	Error     string                             `json:"error,omitempty"`
+}
+
+// matched: The number of selected links
+// changed: The number of links that changed, or would change in a dry run
+// results: One result per selected link, in the order of shortened or oldest first for a filter
+type BulkLinksResponse struct {
+	Message string           `json:"message"`
+	DryRun  bool             `json:"dry_run"`
+	Matched int              `json:"matched"`
+	Changed int              `json:"changed"`
+	Failed  int              `json:"failed"`
+	Results []BulkLinkResult `json:"results"`
+}
+
+// bulkTarget is a link selected by a bulk change, link is nil if it can't be changed and the result tells why
+type bulkTarget struct {
+	link   *models.Link
+	result BulkLinkResult
+	// changed is set once the link changed, or would change in a dry run
+	changed bool
+}
+
+// bulkAudit holds the audit snapshots of a link changed by a bulk change, they are recorded once the response is written
+type bulkAudit struct {
+	link          *models.Link
+	before, after map[string]interface{}
+}
+
+// selectBulkLinks loads the links a bulk change selects, the status code tells why an invalid selection was refused.
+// Links named in shortened that can't be found or managed fail on their own, a filter only selects links the key may manage.
+func selectBulkLinks(db *gorm.DB, ctxValues ContextValues, selection BulkLinkSelection) ([]bulkTarget, int, error) {
+	if (len(selection.Shortened) == 0) == (selection.Filter == nil) {
+		return nil, http.StatusBadRequest, errors.New("either shortened or filter is required")
+	}
+
+	if selection.Filter == nil {
+		if len(selection.Shortened) > lib.MAX_BULK_LINK_CHANGES {
+			return nil, http.StatusBadRequest, fmt.Errorf("shortened must contain at most %d links", lib.MAX_BULK_LINK_CHANGES)
+		}
+
+		targets := make([]bulkTarget, 0, len(selection.Shortened))
+		selected := make(map[uuid.UUID]bool, len(selection.Shortened))
+		for _, shortened := range selection.Shortened {
+			target := bulkTarget{result: BulkLinkResult{Shortened: shortened}}
+			link, err := RetrieveLink(db, shortened)
+			switch {
+			case err != nil:
+				target.result.Error = "Link not found"
+			case !canManageLink(ctxValues, *link):
+				target.result.Error = "Unauthorized to change this link"
+			case selected[link.ID]:
+				target.result.Error = "Link is selected more than once"
+			default:
+				selected[link.ID] = true
+				target.link = link
+			}
+			targets = append(targets, target)
+		}
+		return targets, http.StatusOK, nil
+	}
+
+	request := *selection.Filter
+	if request.Key == "" && request.LinkFilterRequest == (LinkFilterRequest{}) {
+		return nil, http.StatusBadRequest, errors.New("filter must set at least one criterion")
+	}
+	filter, err := request.LinkFilterRequest.filter()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if request.Key != "" {
+		if !ctxValues.IsAdmin && request.Key != ctxValues.SecretKey {
+			return nil, http.StatusUnauthorized, errors.New("Unauthorized to select links by key")
+		}
+		secretKey := models.SearchKeyByKey(db, request.Key)
+		if secretKey == nil {
+			return nil, http.StatusNotFound, errors.New(lib.ERRORS.KeyNotFound)
+		}
+		filter.CreatedBy = &secretKey.ID
+	}
+	// non-admins only select the links they could change one by one
+	if !ctxValues.IsAdmin {
+		keyID := ctxValues.KeyID
+		filter.ManageableBy = &keyID
+	}
+
+	links, err := models.RetrieveFilteredLinks(db, filter, lib.MAX_BULK_LINK_CHANGES)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if len(links) > lib.MAX_BULK_LINK_CHANGES {
+		return nil, http.StatusBadRequest, fmt.Errorf("filter matches more than %d links, narrow it down", lib.MAX_BULK_LINK_CHANGES)
+	}
+
+	targets := make([]bulkTarget, 0, len(links))
+	for i := range links {
+		target := bulkTarget{result: BulkLinkResult{Shortened: linkReference(links[i])}}
+		if canManageLink(ctxValues, links[i]) {
+			target.link = &links[i]
+		} else {
+			target.result.Error = "Unauthorized to change this link"
+		}
+		targets = append(targets, target)
+	}
+	return targets, http.StatusOK, nil
+}
+
+// validate checks that the patch changes something and normalizes its destination domain
+func (p *BulkLinkPatch) validate() error {
+	if p.IsActive == nil && p.ExpiresAt == nil && p.DestinationDomain == "" {
+		return errors.New(lib.ERRORS.NoNewFields)
+	}
+	if p.DestinationDomain != "" {
+		host := strings.ToLower(strings.TrimSpace(p.DestinationDomain))
+		hostname, port, hasPort := host, "", false
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			hostname, port, hasPort = host[:i], host[i+1:], true
+		}
+		if !hostPattern.MatchString(hostname) || (hasPort && !isValidPort(port)) {
+			return errors.New("destination_domain must be a host name with an optional port, e.g. example.com or example.com:8080")
+		}
+		p.DestinationDomain = host
+	}
+	return nil
+}
+
+// isValidPort reports whether a port of a host is a number from 1 to 65535
+func isValidPort(port string) bool {
+	n, err := strconv.ParseUint(port, 10, 16)
+	return err == nil && n > 0
+}
+
+// apply changes a link according to the patch, failing if the changed link isn't valid anymore
+func (p BulkLinkPatch) apply(link *models.Link) error {
+	if p.IsActive != nil {
+		link.IsActive = *p.IsActive
+	}
+	if p.ExpiresAt != nil {
+		// the earliest possible timestamp unsets it, just like /v1/links/update
+		if p.ExpiresAt.Equal(time.Unix(0, 0)) {
+			link.ExpiresAt = nil
+		} else {
+			expiresAt := *p.ExpiresAt
+			link.ExpiresAt = &expiresAt
+		}
+	}
+	if p.DestinationDomain != "" {
+		u, err := url.Parse(link.RedirectTo)
+		if err != nil || u.Host == "" {
+			return errors.New("redirect_to has no host to replace")
+		}
+		// the port of the destination is kept unless the patch names one
+		host := p.DestinationDomain
+		if port := u.Port(); port != "" && !strings.Contains(host, ":") {
+			host += ":" + port
+		}
+		u.Host = host
+		link.RedirectTo = u.String()
+	}
+	// the same invariant as /v1/links/update, a link expiring before it starts would never go live
+	if link.StartsAt != nil && link.ExpiresAt != nil && !link.StartsAt.Before(*link.ExpiresAt) {
+		return errors.New("starts_at must be before expires_at")
+	}
+	return nil
+}
+
+// writeBulkLinksResponse counts the results of a bulk change and writes them
+func writeBulkLinksResponse(w http.ResponseWriter, dryRun bool, message string, targets []bulkTarget) BulkLinksResponse {
+	response := BulkLinksResponse{
+		Message: message,
+		DryRun:  dryRun,
+		Matched: len(targets),
+		Results: make([]BulkLinkResult, 0, len(targets)),
+	}
+	for _, target := range targets {
+		if !target.result.Success {
+			response.Failed++
+		}
+		if target.changed {
+			response.Changed++
+		}
+		response.Results = append(response.Results, target.result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	return response
+}
+
+// BulkUpdateLinksHandler changes many links at once.
+// @Summary Update many links
+// @Description Deactivates, reactivates, sets the expiry of or moves the destination host of the links named in shortened or matched by filter.
+// @Description Every link is checked like in /v1/links/update: non-admin keys can only change the links they created or that live in a namespace they own.
+// @Description Each link is changed on its own and reports its changes or error. With dry_run, the changes are only reported.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body BulkUpdateLinksRequest true "Bulk link update request"
+// @Success 200 {object} BulkLinksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/bulk-update [post]
+func BulkUpdateLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request BulkUpdateLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	err := request.Patch.validate()
+	if err == nil && request.Patch.DestinationDomain != "" && isShortenerHost(utils.LoadEnv(), request.Patch.DestinationDomain) {
+		err = errors.New("cannot redirect to link shortener")
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	db := database.GetDB()
+	targets, status, err := selectBulkLinks(db, ctxValues, request.BulkLinkSelection)
+	if err != nil {
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			message = "Database Error"
+		}
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	var audits []bulkAudit
+
+	for i := range targets {
+		target := &targets[i]
+		if target.link == nil {
+			continue
+		}
+		link := target.link
+		previous := *link
+		before := models.LinkAuditSnapshot(previous)
+
+		if err := request.Patch.apply(link); err != nil {
+			target.result.Error = err.Error()
+			continue
+		}
+		after := models.LinkAuditSnapshot(*link)
+		target.result.Changes = models.BuildAuditDiff(before, after)
+		if request.DryRun || len(target.result.Changes) == 0 {
+			target.result.Success = true
+			target.changed = len(target.result.Changes) > 0
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// only the patched columns are written, the links were loaded before the loop and may have been visited or expired since
+			if err := models.SaveLinkChanges(tx, previous, link); err != nil {
+				return err
+			}
+			return models.RecordLinkRevision(tx, previous, *link, ctxValues.KeyID)
+		})
+		if err != nil {
+			target.result.Changes = nil
+			target.result.Error = "Failed to update link"
+			continue
+		}
+		target.result.Success = true
+		target.changed = true
+		audits = append(audits, bulkAudit{link: link, before: before, after: after})
+	}
+
+	message := "Links updated successfully"
+	if request.DryRun {
+		message = "Dry run, no links were updated"
+	}
+	response := writeBulkLinksResponse(w, request.DryRun, message, targets)
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceLinks,
+		fmt.Sprintf("Bulk updated %d of %d links (dry run: %t). Requested by: '%s'", response.Changed, response.Matched, request.DryRun, ctxValues.SecretKey), r.RemoteAddr)
+
+	for _, audit := range audits {
+		recordAuditEvent(r, ctxValues, models.AuditActionLinkUpdate, models.AuditTargetLink,
+			audit.link.ID.String(), audit.link.Shortened, audit.before, audit.after)
+	}
+}
+
+// BulkDeleteLinksHandler moves many links to the trash at once.
+// @Summary Delete many links
+// @Description Moves the links named in shortened or matched by filter to the trash.
+// @Description Every link is checked like in /v1/links/delete: non-admin keys can only delete the links they created or that live in a namespace they own.
+// @Description Each link is deleted on its own and reports its error. With dry_run, the links that would be deleted are only reported.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body BulkDeleteLinksRequest true "Bulk link delete request"
+// @Success 200 {object} BulkLinksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/bulk-delete [post]
+func BulkDeleteLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request BulkDeleteLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	db := database.GetDB()
+	targets, status, err := selectBulkLinks(db, ctxValues, request.BulkLinkSelection)
+	if err != nil {
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			message = "Database Error"
+		}
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	var audits []bulkAudit
+	for i := range targets {
+		target := &targets[i]
+		if target.link == nil {
+			continue
+		}
+		if !request.DryRun {
+			linkBefore := models.LinkAuditSnapshot(*target.link)
+			// move link to the trash
+			if err := db.Delete(target.link).Error; err != nil {
+				target.result.Error = "Failed to delete link"
+				continue
+			}
+			audits = append(audits, bulkAudit{link: target.link, before: linkBefore})
+		}
+		target.result.Success = true
+		target.changed = true
+	}
+
+	message := "Links moved to trash successfully"
+	if request.DryRun {
+		message = "Dry run, no links were deleted"
+	}
+	response := writeBulkLinksResponse(w, request.DryRun, message, targets)
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceLinks,
+		fmt.Sprintf("Bulk deleted %d of %d links (dry run: %t). Requested by: '%s'", response.Changed, response.Matched, request.DryRun, ctxValues.SecretKey), r.RemoteAddr)
+
+	for _, audit := range audits {
+		recordAuditEvent(r, ctxValues, models.AuditActionLinkDelete, models.AuditTargetLink,
+			audit.link.ID.String(), audit.link.Shortened, audit.before, nil)
+	}
+}
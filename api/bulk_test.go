@@ -0,0 +1,95 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindBatchSlugCollisions(t *testing.T) {
+	requests := []ShortenRequest{
+		{CustomURL: "docs"},
+		{CustomURL: "docs", Domain: "go.example.com"},
+		{CustomURL: ""},
+		{CustomURL: "docs", Namespace: "eng"},
+		{CustomURL: "docs"},
+		{CustomURL: ""},
+		{CustomURL: "docs", Domain: "GO.example.com."},
+		{CustomURL: "Docs"},
+	}
+
+	expected := map[int]int{4: 0, 6: 1}
+	if collisions := findBatchSlugCollisions(requests); !reflect.DeepEqual(collisions, expected) {
+		t.Errorf("findBatchSlugCollisions = %v, expected %v", collisions, expected)
+	}
+}
+
+func TestBulkLinkPatch(t *testing.T) {
+	var empty BulkLinkPatch
+	if err := empty.validate(); err == nil {
+		t.Error("validate accepted an empty patch")
+	}
+	for _, destinationDomain := range []string{"https://new.example.com", "a:b:c", "new.example.com:", "new.example.com:99999", "new example.com"} {
+		invalid := BulkLinkPatch{DestinationDomain: destinationDomain}
+		if err := invalid.validate(); err == nil {
+			t.Errorf("validate accepted %q as destination_domain", destinationDomain)
+		}
+	}
+
+	inactive := false
+	expiresAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	patch := BulkLinkPatch{IsActive: &inactive, ExpiresAt: &expiresAt, DestinationDomain: " New.Example.com "}
+	if err := patch.validate(); err != nil || patch.DestinationDomain != "new.example.com" {
+		t.Fatalf("validate = %v, destination_domain = %q", err, patch.DestinationDomain)
+	}
+
+	link := models.Link{RedirectTo: "https://old.example.com/docs?page=2#intro", IsActive: true}
+	if err := patch.apply(&link); err != nil {
+		t.Fatalf("apply error = %v", err)
+	}
+	if link.RedirectTo != "https://new.example.com/docs?page=2#intro" || link.IsActive || link.ExpiresAt == nil || !link.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("apply = %+v", link)
+	}
+
+	epoch := time.Unix(0, 0)
+	unset := BulkLinkPatch{ExpiresAt: &epoch}
+	if err := unset.apply(&link); err != nil || link.ExpiresAt != nil {
+		t.Errorf("unsetting expires_at = %v, %v", link.ExpiresAt, err)
+	}
+
+	magnet := models.Link{RedirectTo: "magnet:?xt=urn:btih:abc"}
+	if err := patch.apply(&magnet); err == nil {
+		t.Error("apply replaced the host of a URL without one")
+	}
+
+	ports := []struct {
+		redirectTo        string
+		destinationDomain string
+		expected          string
+	}{
+		{"http://old.example.com:8080/x", "new.example.com", "http://new.example.com:8080/x"},
+		{"http://old.example.com:8080/x", "new.example.com:9090", "http://new.example.com:9090/x"},
+		{"http://old.example.com/x", "new.example.com:9090", "http://new.example.com:9090/x"},
+	}
+	for _, test := range ports {
+		portPatch := BulkLinkPatch{DestinationDomain: test.destinationDomain}
+		moved := models.Link{RedirectTo: test.redirectTo}
+		if err := portPatch.validate(); err != nil {
+			t.Fatalf("validate(%q) error = %v", test.destinationDomain, err)
+		}
+		if err := portPatch.apply(&moved); err != nil || moved.RedirectTo != test.expected {
+			t.Errorf("moving %q to %q = %q, %v, expected %q", test.redirectTo, test.destinationDomain, moved.RedirectTo, err, test.expected)
+		}
+	}
+
+	startsAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	scheduled := models.Link{RedirectTo: "https://old.example.com/launch", StartsAt: &startsAt}
+	if err := (BulkLinkPatch{ExpiresAt: &expiresAt}).apply(&scheduled); err == nil {
+		t.Error("apply set expires_at before starts_at")
+	}
+	later := startsAt.Add(24 * time.Hour)
+	if err := (BulkLinkPatch{ExpiresAt: &later}).apply(&scheduled); err != nil {
+		t.Errorf("apply error = %v, expected expires_at after starts_at to be accepted", err)
+	}
+}
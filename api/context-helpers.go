@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 )
 
@@ -25,3 +26,24 @@ func CheckUnauthorized(w http.ResponseWriter, r *http.Request) bool {
 	}
 	return false
 }
+
+// GetClientIP returns the IP address of the client without the port, or an empty string if it can't be parsed.
+func GetClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
+
+// GetClientKey identifies the client of a request for rate limiting: its IP address, or the raw remote address
+// if that can't be parsed, so that such clients aren't all counted as one.
+func GetClientKey(r *http.Request) string {
+	if ip := GetClientIP(r); ip != "" {
+		return ip
+	}
+	return r.RemoteAddr
+}
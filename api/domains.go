@@ -0,0 +1,558 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var hostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// requestDomain returns the registered domain a request was sent to, or nil for the default domain
+func requestDomain(db *gorm.DB, r *http.Request) (*models.Domain, error) {
+	domain, err := models.FindDomainByHost(db, r.Host)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return domain, err
+}
+
+// domainID returns the ID of a domain, or nil for the default domain
+func domainID(domain *models.Domain) *uuid.UUID {
+	if domain == nil {
+		return nil
+	}
+	return &domain.ID
+}
+
+// isShortenerHost reports whether a host is served by this deployment, either as PUBLIC_SITE_URL or a registered domain
+func isShortenerHost(env *utils.Env, host string) bool {
+	host = models.NormalizeHost(host)
+	if host == "" {
+		return false
+	}
+
+	if env.PUBLIC_SITE_URL != "" {
+		publicURL, err := url.Parse(env.PUBLIC_SITE_URL)
+		if err == nil && models.NormalizeHost(publicURL.Host) == host {
+			return true
+		}
+		// PUBLIC_SITE_URL may be set to a bare host name
+		if models.NormalizeHost(env.PUBLIC_SITE_URL) == host {
+			return true
+		}
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		return false
+	}
+	_, err := models.FindDomainByHost(db, host)
+	return err == nil
+}
+
+// shortURLBase returns the scheme and host links on a domain are reachable at
+func shortURLBase(env *utils.Env, domain *models.Domain) (string, error) {
+	if env.PUBLIC_SITE_URL == "" {
+		return "", errors.New("PUBLIC_SITE_URL is not configured")
+	}
+	if domain == nil {
+		return strings.TrimSuffix(env.PUBLIC_SITE_URL, "/"), nil
+	}
+
+	scheme := "https"
+	if publicURL, err := url.Parse(env.PUBLIC_SITE_URL); err == nil && publicURL.Scheme != "" {
+		scheme = publicURL.Scheme
+	}
+	return scheme + "://" + domain.Host, nil
+}
+
+// linkDomainHost returns the host of a link's domain, or nil for the default domain
+func linkDomainHost(link models.Link) *string {
+	if link.Domain == nil {
+		return nil
+	}
+	return &link.Domain.Host
+}
+
+type DomainResponse struct {
+	ID                  uuid.UUID      `json:"id"`
+	Host                string         `json:"host"`
+	DefaultRedirectType int            `json:"default_redirect_type"`
+	NotFoundURL         *string        `json:"not_found_url"`
+	AllowedKeys         []KeyReference `json:"allowed_keys"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+}
+
+// convert models.Domain to DomainResponse
+func ToDomainResponse(d models.Domain) DomainResponse {
+	return DomainResponse{
+		ID:                  d.ID,
+		Host:                d.Host,
+		DefaultRedirectType: d.DefaultRedirectType,
+		NotFoundURL:         d.NotFoundURL,
+		AllowedKeys:         toKeyReferences(d.AllowedKeys),
+		CreatedAt:           d.CreatedAt,
+		UpdatedAt:           d.UpdatedAt,
+	}
+}
+
+// domainAuditSnapshot returns the audited fields of a domain
+func domainAuditSnapshot(d models.Domain) map[string]interface{} {
+	allowedKeys := make([]string, 0, len(d.AllowedKeys))
+	for _, key := range d.AllowedKeys {
+		allowedKeys = append(allowedKeys, key.ID.String())
+	}
+	return map[string]interface{}{
+		"host":                  d.Host,
+		"default_redirect_type": d.DefaultRedirectType,
+		"not_found_url":         d.NotFoundURL,
+		"allowed_keys":          allowedKeys,
+	}
+}
+
+// resolveKey looks up a key by its ID or name, so that other keys can be referenced without knowing their secret
+func resolveKey(db *gorm.DB, reference string) *models.SecretKey {
+	if id, err := uuid.Parse(reference); err == nil {
+		return models.SearchKeyByID(db, id)
+	}
+	return models.SearchKeyByName(db, reference)
+}
+
+// resolveKeys looks up the keys granted access to a domain or namespace by their IDs or names
+func resolveKeys(db *gorm.DB, references []string) ([]models.SecretKey, error) {
+	allowedKeys := make([]models.SecretKey, 0, len(references))
+	for _, reference := range references {
+		secretKey := resolveKey(db, reference)
+		if secretKey == nil {
+			return nil, fmt.Errorf("key '%s' not found", reference)
+		}
+		allowedKeys = append(allowedKeys, *secretKey)
+	}
+	return allowedKeys, nil
+}
+
+type RetrieveAllDomainsResponse struct {
+	Message string           `json:"message"`
+	Domains []DomainResponse `json:"domains"`
+	models.PageInfo
+}
+
+// RetrieveAllDomainsHandler retrieves all domains. Requires admin permissions.
+// @Summary Retrieve all domains
+// @Description Retrieves every short domain served by this deployment with its settings.
+// @Tags domains,admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param cursor query string false "The next_cursor of the previous page"
+// @Param sort query string false "host (default) or created_at"
+// @Param order query string false "asc or desc, the default is asc for host and desc for created_at"
+// @Success 200 {object} RetrieveAllDomainsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/domains/retrieve-all [get]
+func RetrieveAllDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	listRequest, err := listRequestFromQuery(r.URL.Query())
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	domains, page, err := models.RetrieveAllDomains(database.GetDB(), listRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	responses := make([]DomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		responses = append(responses, ToDomainResponse(domain))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetrieveAllDomainsResponse{
+		Message:  "Domains retrieved successfully",
+		Domains:  responses,
+		PageInfo: page,
+	})
+}
+
+// host: The host name visitors use, e.g. "go.example.com". Ports are ignored
+// default_redirect_type: The redirect status code of the domain's links that don't set their own (301, 302, 307 or 308), 0 uses the server default
+// not_found_url: Where visitors of unknown slugs on the domain are sent, if empty, the 404 page is shown
+// allowed_keys: The IDs or names of the keys that may create links on the domain, if empty, every key may
+type CreateDomainRequest struct {
+	Host                string   `json:"host"`
+	DefaultRedirectType int      `json:"default_redirect_type,omitempty"`
+	NotFoundURL         string   `json:"not_found_url,omitempty"`
+	AllowedKeys         []string `json:"allowed_keys,omitempty"`
+}
+
+// CreateDomainHandler registers a short domain. Requires admin permissions.
+// @Summary Create a domain
+// @Description Registers a short domain. Requests whose Host matches it resolve slugs on that domain, slugs can repeat across domains.
+// @Tags domains,admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateDomainRequest true "Domain creation request"
+// @Success 200 {object} DomainResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/domains/create [post]
+func CreateDomainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	db := database.GetDB()
+
+	domain := models.Domain{Host: models.NormalizeHost(request.Host)}
+	var allowedKeys []models.SecretKey
+	err := applyDomainSettings(db, &domain, &allowedKeys, &request.DefaultRedirectType, &request.NotFoundURL, request.AllowedKeys)
+	if err == nil && !hostPattern.MatchString(domain.Host) {
+		err = errors.New("host must be a valid host name such as \"go.example.com\"")
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if _, err := models.FindDomainByHost(db, domain.Host); err == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusConflict,
+			Message:   "A domain with this host already exists",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if err := models.SaveDomain(db, &domain, allowedKeys); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to create domain",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToDomainResponse(domain))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionDomainCreate, models.AuditTargetDomain,
+		domain.ID.String(), domain.Host, nil, domainAuditSnapshot(domain))
+}
+
+// host: The domain to update
+// default_redirect_type, not_found_url, allowed_keys: The new settings, fields that are left out stay unchanged.
+// An empty not_found_url shows the 404 page again, an empty allowed_keys list allows every key again
+type UpdateDomainRequest struct {
+	Host                string    `json:"host"`
+	DefaultRedirectType *int      `json:"default_redirect_type,omitempty"`
+	NotFoundURL         *string   `json:"not_found_url,omitempty"`
+	AllowedKeys         *[]string `json:"allowed_keys,omitempty"`
+}
+
+// UpdateDomainHandler changes the settings of a domain. Requires admin permissions.
+// @Summary Update a domain
+// @Description Changes the default redirect code, the not found URL or the allowed keys of a domain.
+// @Tags domains,admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body UpdateDomainRequest true "Domain update request"
+// @Success 200 {object} DomainResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/domains/update [post]
+func UpdateDomainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	db := database.GetDB()
+
+	domain := retrieveDomain(w, r, ctxValues, db, request.Host)
+	if domain == nil {
+		return
+	}
+	domainBefore := domainAuditSnapshot(*domain)
+
+	allowedKeys := domain.AllowedKeys
+	var keys []string
+	if request.AllowedKeys != nil {
+		keys = *request.AllowedKeys
+		allowedKeys = nil
+	}
+	if err := applyDomainSettings(db, domain, &allowedKeys, request.DefaultRedirectType, request.NotFoundURL, keys); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if err := models.SaveDomain(db, domain, allowedKeys); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to update domain",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToDomainResponse(*domain))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionDomainUpdate, models.AuditTargetDomain,
+		domain.ID.String(), domain.Host, domainBefore, domainAuditSnapshot(*domain))
+}
+
+type DeleteDomainRequest struct {
+	Host string `json:"host"`
+}
+
+type DeleteDomainResponse struct {
+	Message string `json:"message"`
+}
+
+// DeleteDomainHandler deletes a domain that has no links. Requires admin permissions.
+// @Summary Delete a domain
+// @Description Deletes a domain. Domains that still have links, including trashed ones, can't be deleted.
+// @Tags domains,admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body DeleteDomainRequest true "Domain deletion request"
+// @Success 200 {object} DeleteDomainResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/domains/delete [post]
+func DeleteDomainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request DeleteDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	db := database.GetDB()
+
+	domain := retrieveDomain(w, r, ctxValues, db, request.Host)
+	if domain == nil {
+		return
+	}
+
+	count, err := models.CountDomainLinks(db, domain.ID)
+	if err == nil && count > 0 {
+		config := ErrorResponseConfig{
+			Status:    http.StatusConflict,
+			Message:   fmt.Sprintf("The domain still has %d links, purge or move them first", count),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if err == nil {
+		err = models.DeleteDomain(db, domain)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to delete domain",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeleteDomainResponse{Message: "Domain deleted successfully"})
+
+	recordAuditEvent(r, ctxValues, models.AuditActionDomainDelete, models.AuditTargetDomain,
+		domain.ID.String(), domain.Host, domainAuditSnapshot(*domain), nil)
+}
+
+// retrieveDomain loads a domain by its host. It writes the error response itself and returns nil if it isn't found.
+func retrieveDomain(w http.ResponseWriter, r *http.Request, ctxValues ContextValues, db *gorm.DB, host string) *models.Domain {
+	domain, err := models.FindDomainByHost(db, host)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Domain not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceDomains,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Host: %s, Error: %v", host, err),
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			config.Status = http.StatusInternalServerError
+			config.Message = lib.ERRORS.Database
+		}
+		writeErrorResponse(w, config)
+		return nil
+	}
+	return domain
+}
+
+// applyDomainSettings validates the given settings and applies them to a domain, nil settings are left unchanged.
+// allowedKeys is only replaced when keys is non-nil.
+func applyDomainSettings(db *gorm.DB, domain *models.Domain, allowedKeys *[]models.SecretKey, redirectType *int, notFoundURL *string, keys []string) error {
+	if redirectType != nil {
+		if !isValidRedirectType(*redirectType) {
+			return errors.New("default_redirect_type must be one of 301, 302, 307 or 308")
+		}
+		domain.DefaultRedirectType = *redirectType
+	}
+
+	if notFoundURL != nil {
+		if *notFoundURL == "" {
+			domain.NotFoundURL = nil
+		} else {
+			normalized, err := validateAndNormalizeURL(*notFoundURL)
+			if err != nil {
+				return fmt.Errorf("invalid not_found_url: %v", err)
+			}
+			domain.NotFoundURL = &normalized
+		}
+	}
+
+	if keys != nil {
+		resolved, err := resolveKeys(db, keys)
+		if err != nil {
+			return err
+		}
+		*allowedKeys = resolved
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"log"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// geoIPReader looks up visitor countries for country rules, it's nil if no GeoIP database is configured
+var geoIPReader *geoip2.Reader
+
+// openGeoIPDatabase opens the local MaxMind (GeoLite2 or GeoIP2) country or city database at path
+func openGeoIPDatabase(path string) {
+	if path == "" {
+		return
+	}
+
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		log.Printf("🛈  Couldn't open GeoIP database '%s', country rules won't match: %v", path, err)
+		return
+	}
+	geoIPReader = reader
+}
+
+// lookupCountry returns the ISO 3166-1 alpha-2 country code of an IP address, or an empty string if it's unknown
+func lookupCountry(ipAddress string) string {
+	if geoIPReader == nil || ipAddress == "" {
+		return ""
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return ""
+	}
+
+	record, err := geoIPReader.Country(ip)
+	if err != nil {
+		return ""
+	}
+	return record.Country.IsoCode
+}
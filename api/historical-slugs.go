@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"net/http"
+	"time"
+)
+
+type RetrieveHistoricalSlugsRequest struct {
+	Shortened string `json:"shortened"`
+}
+
+type HistoricalSlugResponse struct {
+	Slug              string    `json:"slug"`
+	RedirectToCurrent bool      `json:"redirect_to_current"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type RetrieveHistoricalSlugsResponse struct {
+	Message         string                   `json:"message"`
+	HistoricalSlugs []HistoricalSlugResponse `json:"historical_slugs"`
+}
+
+// RetrieveHistoricalSlugsHandler lists the old slugs of a renamed link.
+// @Summary Retrieve historical slugs
+// @Description Lists the old slugs of a renamed link. They keep redirecting to the link and stay reserved until released.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RetrieveHistoricalSlugsRequest true "Historical slugs request"
+// @Success 200 {object} RetrieveHistoricalSlugsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/historical-slugs [post]
+func RetrieveHistoricalSlugsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RetrieveHistoricalSlugsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	historicalSlugs, err := models.RetrieveHistoricalSlugs(database.GetDB(), link.ID)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Database Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	responseSlugs := make([]HistoricalSlugResponse, 0, len(historicalSlugs))
+	for _, historicalSlug := range historicalSlugs {
+		responseSlugs = append(responseSlugs, HistoricalSlugResponse{
+			Slug:              historicalSlug.Slug,
+			RedirectToCurrent: historicalSlug.RedirectToCurrent,
+			CreatedAt:         historicalSlug.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetrieveHistoricalSlugsResponse{
+		Message:         "Historical slugs retrieved successfully",
+		HistoricalSlugs: responseSlugs,
+	})
+}
+
+type ReleaseHistoricalSlugRequest struct {
+	Shortened string `json:"shortened"`
+	Slug      string `json:"slug"`
+}
+
+type ReleaseHistoricalSlugResponse struct {
+	Message string `json:"message"`
+}
+
+// ReleaseHistoricalSlugHandler releases an old slug of a renamed link.
+// @Summary Release a historical slug
+// @Description Stops an old slug of a renamed link from redirecting and makes it available to be claimed again.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ReleaseHistoricalSlugRequest true "Historical slug release request"
+// @Success 200 {object} ReleaseHistoricalSlugResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/release-historical-slug [post]
+func ReleaseHistoricalSlugHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request ReleaseHistoricalSlugRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	released, err := models.ReleaseHistoricalSlug(database.GetDB(), link.ID, request.Slug)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to release historical slug",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if !released {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Historical slug not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Shortened: %s, Slug: %s", request.Shortened, request.Slug),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReleaseHistoricalSlugResponse{Message: "Historical slug released successfully"})
+
+	recordAuditEvent(r, ctxValues, models.AuditActionSlugRelease, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, map[string]interface{}{"historical_slug": request.Slug}, nil)
+}
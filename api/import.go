@@ -0,0 +1,414 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/importer"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportConflictPolicy decides what happens to imported links whose slug can't be kept
+type ImportConflictPolicy string
+
+const (
+	// skip doesn't import the link and reports the conflict
+	ImportConflictSkip ImportConflictPolicy = "skip"
+	// rename imports the link with a generated slug
+	ImportConflictRename ImportConflictPolicy = "rename"
+)
+
+// ImportStatus is the outcome of importing one link
+type ImportStatus string
+
+const (
+	ImportStatusCreated  ImportStatus = "created"
+	ImportStatusRenamed  ImportStatus = "renamed"
+	ImportStatusConflict ImportStatus = "conflict"
+	ImportStatusFailed   ImportStatus = "failed"
+)
+
+// errImportDryRun rolls back the transaction of a dry run
+var errImportDryRun = errors.New("dry run")
+
+// ImportOptions configures where and for whom links are imported
+type ImportOptions struct {
+	// KeyID is the key the imported links are assigned to
+	KeyID uuid.UUID
+	// Domain and Namespace are the host and name the links are imported into, empty for the defaults
+	Domain     string
+	Namespace  string
+	OnConflict ImportConflictPolicy
+	// DryRun reports what would be imported without creating anything
+	DryRun bool
+}
+
+// format: csv, json, yourls, shlink, bitly or nginx
+// data: The content of the exported file
+// key: The key the links are assigned to, defaults to the requesting key
+// domain: The host of the domain the links are imported into, empty for the default domain
+// namespace: The namespace the links are imported into, empty for top-level links
+// on_conflict: What happens to links whose slug is taken or invalid: skip (default) reports them, rename generates a new slug
+// dry_run: Only report what would be imported
+type ImportLinksRequest struct {
+	Format     importer.Format      `json:"format"`
+	Data       string               `json:"data"`
+	Key        string               `json:"key,omitempty"`
+	Domain     string               `json:"domain,omitempty"`
+	Namespace  string               `json:"namespace,omitempty"`
+	OnConflict ImportConflictPolicy `json:"on_conflict,omitempty"`
+	DryRun     bool                 `json:"dry_run"`
+}
+
+// position: The line of CSV and nginx links, the index of JSON links, starting at 1
+// shortened: The slug in the export
+// status: created, renamed, conflict or failed
+// imported_as: The slug the link was imported as
+// error: Why the slug wasn't kept or the link wasn't imported
+type ImportLinkResult struct {
+	Position   int          `json:"position"`
+	Shortened  string       `json:"shortened"`
+	RedirectTo string       `json:"redirect_to"`
+	Status     ImportStatus `json:"status"`
+	ImportedAs string       `json:"imported_as,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// results: One result per link of the export, in the order of the export
+type ImportLinksResponse struct {
+	Message   string             `json:"message"`
+	DryRun    bool               `json:"dry_run"`
+	Created   int                `json:"created"`
+	Renamed   int                `json:"renamed"`
+	Conflicts int                `json:"conflicts"`
+	Failed    int                `json:"failed"`
+	Results   []ImportLinkResult `json:"results"`
+}
+
+// importTags turns the tags of other shorteners into valid tag names, e.g. "Spring Sale" into "spring-sale"
+func importTags(tags []string) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if name := strings.Join(strings.Fields(tag), "-"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// importTitle shortens titles, which other shorteners often copy from the destination page, to the title limit
+func importTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) <= maxLinkTitleLength {
+		return title
+	}
+	return string([]rune(title)[:maxLinkTitleLength])
+}
+
+// ImportLinks creates a link for every record of an export, keeping its slug, creation date and visits.
+// Each link is created on its own, records that fail or conflict are reported without affecting the others.
+// The created links are returned, none for a dry run.
+func ImportLinks(db *gorm.DB, records []importer.Record, opts ImportOptions) (ImportLinksResponse, []models.Link, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ImportConflictSkip
+	}
+	if opts.OnConflict != ImportConflictSkip && opts.OnConflict != ImportConflictRename {
+		return ImportLinksResponse{}, nil, errors.New("on_conflict must be skip or rename")
+	}
+
+	scopeRequest := ShortenRequest{Domain: opts.Domain, Namespace: opts.Namespace}
+	domain, namespace, _, err := resolveShortenScope(db, scopeRequest, opts.KeyID, true)
+	if err != nil {
+		return ImportLinksResponse{}, nil, err
+	}
+	scope := linkPath{Domain: domain, Namespace: namespace}.Scope()
+
+	response := ImportLinksResponse{DryRun: opts.DryRun, Results: make([]ImportLinkResult, len(records))}
+	var created []models.Link
+
+	// create creates the link of a record with the given slug, a generated one if it's empty.
+	// conflict tells why the slug of the record wasn't kept.
+	create := func(tx *gorm.DB, i int, slug string, conflict string) {
+		record, result := records[i], &response.Results[i]
+		req := ShortenRequest{
+			CustomURL:  slug,
+			RedirectTo: record.RedirectTo,
+			Tags:       importTags(record.Tags),
+			Folder:     record.Folder,
+			Title:      importTitle(record.Title),
+			Notes:      record.Notes,
+		}
+		var link *models.Link
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			res, err := CreateLink(tx, req, opts.KeyID, domain, namespace)
+			if err != nil {
+				return err
+			}
+			link, err = retrieveScopedLink(tx, scope, res.ShortenedURL)
+			if err != nil {
+				return fmt.Errorf("database error: %v", err)
+			}
+
+			// keep the history of the link from the other shortener
+			if record.CreatedAt != nil {
+				link.CreatedAt = *record.CreatedAt
+			}
+			link.Visits = record.Visits
+			return tx.Model(link).UpdateColumns(map[string]interface{}{
+				"created_at": link.CreatedAt,
+				"visits":     link.Visits,
+			}).Error
+		})
+		if err != nil {
+			result.Status = ImportStatusFailed
+			result.Error = err.Error()
+			return
+		}
+
+		result.ImportedAs = link.Shortened
+		result.Status = ImportStatusCreated
+		if conflict != "" {
+			result.Status = ImportStatusRenamed
+			result.Error = conflict
+		}
+		created = append(created, *link)
+	}
+
+	importAll := func(tx *gorm.DB) error {
+		// claimed maps the imported slugs to the position of their link in the export
+		claimed := make(map[string]int, len(records))
+		// links needing a generated slug are created last, so that it can't take the slug of a later link
+		var generated []int
+		conflicts := make(map[int]string)
+
+		for i, record := range records {
+			result := &response.Results[i]
+			*result = ImportLinkResult{Position: record.Position, Shortened: record.Slug, RedirectTo: record.RedirectTo}
+			if record.Err != nil {
+				result.Status = ImportStatusFailed
+				result.Error = record.Err.Error()
+				continue
+			}
+
+			// conflict tells why the slug can't be kept, the link is then skipped or renamed
+			var conflict string
+			switch {
+			case record.Slug == "":
+			case !isAlphanumeric(record.Slug):
+				conflict = "slug must be alphanumeric"
+			case claimed[record.Slug] > 0:
+				conflict = fmt.Sprintf("slug is already used by the link at position %d", claimed[record.Slug])
+			default:
+				taken, err := isShortenedURLTaken(tx, scope, record.Slug)
+				if err != nil {
+					return err
+				}
+				if taken {
+					conflict = "slug is already in use, or is reserved"
+				}
+			}
+
+			if conflict != "" && opts.OnConflict == ImportConflictSkip {
+				result.Status = ImportStatusConflict
+				result.Error = conflict
+				continue
+			}
+			if conflict != "" || record.Slug == "" {
+				conflicts[i] = conflict
+				generated = append(generated, i)
+				continue
+			}
+
+			create(tx, i, record.Slug, "")
+			if result.Status == ImportStatusCreated {
+				claimed[record.Slug] = record.Position
+			}
+		}
+
+		for _, i := range generated {
+			create(tx, i, "", conflicts[i])
+		}
+		return nil
+	}
+
+	// a dry run imports like a real one, and rolls everything back
+	if opts.DryRun {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := importAll(tx); err != nil {
+				return err
+			}
+			return errImportDryRun
+		})
+		if errors.Is(err, errImportDryRun) {
+			err = nil
+		}
+		created = nil
+	} else {
+		err = importAll(db)
+	}
+	if err != nil {
+		return ImportLinksResponse{}, nil, fmt.Errorf("database error: %v", err)
+	}
+
+	for _, result := range response.Results {
+		switch result.Status {
+		case ImportStatusCreated:
+			response.Created++
+		case ImportStatusRenamed:
+			response.Renamed++
+		case ImportStatusConflict:
+			response.Conflicts++
+		case ImportStatusFailed:
+			response.Failed++
+		}
+	}
+	response.Message = "Links imported successfully"
+	if opts.DryRun {
+		response.Message = "Dry run, no links were imported"
+	}
+	return response, created, nil
+}
+
+// ImportLinksHandler imports the links exported from another shortener.
+// @Summary Import links
+// @Description Imports links from an export in this shortener's own CSV or JSON schema, from YOURLS, Shlink or Bitly (CSV or JSON), or from an nginx map file.
+// @Description Slugs, destinations, creation dates, visit counts, titles and tags are kept where the export has them, and the links are assigned to key.
+// @Description Links whose slug is taken or invalid are reported as conflicts, or imported with a generated slug if on_conflict is rename.
+// @Description Each link is imported on its own and reports its error. With dry_run, nothing is created.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ImportLinksRequest true "Import request"
+// @Success 200 {object} ImportLinksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/import [post]
+func ImportLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request ImportLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	records, err := importer.Parse(request.Format, strings.NewReader(request.Data))
+	if err == nil && len(records) == 0 {
+		err = errors.New("the export contains no links")
+	}
+	if err == nil && len(records) > lib.MAX_IMPORT_LINKS {
+		err = fmt.Errorf("an import may contain at most %d links", lib.MAX_IMPORT_LINKS)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   lib.ERRORS.Database,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	keyID := ctxValues.KeyID
+	if request.Key != "" {
+		secretKey := models.SearchKeyByKey(db, request.Key)
+		if secretKey == nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusNotFound,
+				Message:   lib.ERRORS.KeyNotFound,
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		keyID = secretKey.ID
+	}
+
+	opts := ImportOptions{
+		KeyID:      keyID,
+		Domain:     request.Domain,
+		Namespace:  request.Namespace,
+		OnConflict: request.OnConflict,
+		DryRun:     request.DryRun,
+	}
+	response, created, err := ImportLinks(db, records, opts)
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if strings.HasPrefix(message, "database error") {
+			status, message = http.StatusInternalServerError, "Database Error"
+		}
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceLinks,
+		fmt.Sprintf("Imported %d of %d %s links (dry run: %t). Requested by: '%s'", response.Created+response.Renamed, len(records), request.Format, request.DryRun, ctxValues.SecretKey), r.RemoteAddr)
+
+	for _, link := range created {
+		recordAuditEvent(r, ctxValues, models.AuditActionLinkCreate, models.AuditTargetLink,
+			link.ID.String(), link.Shortened, nil, models.LinkAuditSnapshot(link))
+	}
+}
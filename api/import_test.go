@@ -0,0 +1,28 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestImportTags(t *testing.T) {
+	tags := importTags([]string{"Spring  Sale", " ", "eng"})
+	if expected := []string{"Spring-Sale", "eng"}; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("importTags = %v, expected %v", tags, expected)
+	}
+	if normalized, err := normalizeTags(tags); err != nil || !reflect.DeepEqual(normalized, []string{"eng", "spring-sale"}) {
+		t.Errorf("normalizeTags(importTags) = %v, %v", normalized, err)
+	}
+}
+
+func TestImportTitle(t *testing.T) {
+	if title := importTitle(" Docs "); title != "Docs" {
+		t.Errorf("importTitle = %q, expected %q", title, "Docs")
+	}
+	long := strings.Repeat("é", maxLinkTitleLength+10)
+	if title := importTitle(long); utf8.RuneCountInString(title) != maxLinkTitleLength || !utf8.ValidString(title) {
+		t.Errorf("importTitle kept %d characters, expected %d", utf8.RuneCountInString(title), maxLinkTitleLength)
+	}
+}
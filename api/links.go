@@ -22,14 +22,49 @@ import (
 // custom_url: The URL to be shortened, if empty, the URL will be generated
 // redirect_to: The URL to redirect to after the link is shortened
 // expires_at: The expiration date of the link
+// redirect_type: The HTTP status code to redirect with (301, 302, 307 or 308), if empty, the server default is used
+// password: Visitors must enter this password before being redirected, if empty, the link is not protected
+// max_visits: The link is deactivated after this many visits, use 1 for one-time links. If empty, visits are unlimited
+// starts_at: The link only goes live at this time, its slug is reserved until then. If empty, the link is live immediately
+// prelaunch_url: The URL visitors are sent to before starts_at, if empty, a "coming soon" page is shown
+// fallback_url: The URL visitors are sent to once the link expires or is deactivated, if empty, the key's or server's fallback is used
+// forward_query: How the query string of a visit is merged into redirect_to: none (default), incoming (visit wins), destination (redirect_to wins) or allowlist
+// forward_query_keys: The query keys forwarded by the allowlist policy
+// domain: The registered short domain to create the link on, e.g. "go.example.com". If empty, the default domain is used
+// tags: Tags to organize the link by, e.g. "spring-campaign". Tags are lowercase letters, digits, "-" or "_" and are created on first use
+// folder: An optional "/" separated folder path for the link, e.g. "marketing/2024"
+// title: An optional title describing the link, it is searchable but never shown to visitors
+// notes: Optional notes about the link, they are searchable but never shown to visitors
+// namespace: The namespace to create the link in, its slug is then reached at /{namespace}/{custom_url}. Requires being the namespace's owner or a member
+// path_mode: What happens to path segments after the slug: exact (default, not found), template (fill {1} or {name} placeholders of redirect_to) or passthrough (append them to redirect_to)
 type ShortenRequest struct {
-	CustomURL  string     `json:"custom_url"`
-	RedirectTo string     `json:"redirect_to"`
-	ExpiresAt  *time.Time `json:"expires_at"`
+	CustomURL        string                    `json:"custom_url"`
+	RedirectTo       string                    `json:"redirect_to"`
+	ExpiresAt        *time.Time                `json:"expires_at"`
+	RedirectType     int                       `json:"redirect_type,omitempty"`
+	Password         string                    `json:"password,omitempty"`
+	MaxVisits        *int                      `json:"max_visits,omitempty"`
+	StartsAt         *time.Time                `json:"starts_at,omitempty"`
+	PrelaunchURL     string                    `json:"prelaunch_url,omitempty"`
+	FallbackURL      string                    `json:"fallback_url,omitempty"`
+	ForwardQuery     models.QueryForwardPolicy `json:"forward_query,omitempty"`
+	ForwardQueryKeys []string                  `json:"forward_query_keys,omitempty"`
+	PathMode         models.LinkPathMode       `json:"path_mode,omitempty"`
+	Domain           string                    `json:"domain,omitempty"`
+	Namespace        string                    `json:"namespace,omitempty"`
+	Tags             []string                  `json:"tags,omitempty"`
+	Folder           string                    `json:"folder,omitempty"`
+	Title            string                    `json:"title,omitempty"`
+	Notes            string                    `json:"notes,omitempty"`
 }
 
+// domain: The host of the link's domain, empty for the default domain.
+// Other endpoints refer to links on other domains as "domain/shortened".
+// namespace: The namespace of the link, empty for top-level links. Other endpoints refer to them as "namespace/shortened".
 type ShortenResponse struct {
 	ShortenedURL string `json:"shortened"`
+	Domain       string `json:"domain,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
 }
 
 // ShortenHandler shortens a URL.
@@ -43,6 +78,7 @@ type ShortenResponse struct {
 // @Success 200 {object} ShortenResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 405 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /v1/links/shorten [post]
@@ -113,7 +149,26 @@ func ShortenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := CreateLink(database.GetDB(), request, secretKey.ID)
+	domain, namespace, status, err := resolveShortenScope(db, request, secretKey.ID, ctxValues.IsAdmin)
+	if err != nil {
+		logType := models.LogTypeError
+		if status == http.StatusForbidden {
+			logType = models.LogTypeWarning
+		}
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   err.Error(),
+			LogType:   logType,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Domain: '%s', namespace: '%s'", request.Domain, request.Namespace),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	res, err := CreateLink(database.GetDB(), request, secretKey.ID, domain, namespace)
 	if err != nil {
 		config := ErrorResponseConfig{
 			Status:    http.StatusInternalServerError,
@@ -133,9 +188,48 @@ func ShortenHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Server Error", http.StatusInternalServerError)
 		return
 	}
+
+	path := linkPath{Domain: domain, Namespace: namespace}
+	if link, err := retrieveScopedLink(db, path.Scope(), res.ShortenedURL); err == nil {
+		recordAuditEvent(r, ctxValues, models.AuditActionLinkCreate, models.AuditTargetLink,
+			link.ID.String(), link.Shortened, nil, models.LinkAuditSnapshot(*link))
+	}
 }
 
-func CreateLink(db *gorm.DB, req ShortenRequest, createdBy uuid.UUID) (*ShortenResponse, error) {
+// resolveShortenScope finds the domain and namespace a shorten request creates its link in, nil for the defaults,
+// and checks that the key may create links there. The status code tells why they can't be used.
+func resolveShortenScope(db *gorm.DB, req ShortenRequest, keyID uuid.UUID, isAdmin bool) (*models.Domain, *models.Namespace, int, error) {
+	var domain *models.Domain
+	if req.Domain != "" {
+		var err error
+		domain, err = models.FindDomainByHost(db, req.Domain)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, errors.New("Domain not found")
+		}
+		if !isAdmin && !models.DomainAllowsKey(*domain, keyID) {
+			return nil, nil, http.StatusForbidden, errors.New("This key may not create links on this domain")
+		}
+	}
+
+	var namespace *models.Namespace
+	if req.Namespace != "" {
+		var err error
+		namespace, err = models.FindNamespace(db, req.Namespace)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, errors.New("Namespace not found")
+		}
+		if !isAdmin && !models.NamespaceAllowsKey(*namespace, keyID) {
+			return nil, nil, http.StatusForbidden, errors.New("This key may not create links in this namespace")
+		}
+	}
+
+	return domain, namespace, http.StatusOK, nil
+}
+
+// CreateLink validates a shorten request and creates the link on the given domain, nil being the default domain,
+// and inside the given namespace, nil for a top-level link.
+// The caller is responsible for checking that the key may use the domain and namespace.
+func CreateLink(db *gorm.DB, req ShortenRequest, createdBy uuid.UUID, domain *models.Domain, namespace *models.Namespace) (*ShortenResponse, error) {
 	// Validate RedirectTo
 	if req.RedirectTo == "" {
 		return nil, errors.New("redirect_to is required")
@@ -146,6 +240,77 @@ func CreateLink(db *gorm.DB, req ShortenRequest, createdBy uuid.UUID) (*ShortenR
 		return nil, fmt.Errorf("invalid redirect_to: %v", err)
 	}
 
+	if !isValidRedirectType(req.RedirectType) {
+		return nil, errors.New("redirect_type must be one of 301, 302, 307 or 308")
+	}
+
+	passwordHash, err := hashLinkPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.MaxVisits != nil && *req.MaxVisits < 1 {
+		return nil, errors.New("max_visits must be at least 1")
+	}
+
+	if req.StartsAt != nil && req.ExpiresAt != nil && !req.StartsAt.Before(*req.ExpiresAt) {
+		return nil, errors.New("starts_at must be before expires_at")
+	}
+
+	var prelaunchURL *string
+	if req.PrelaunchURL != "" {
+		normalized, err := validateAndNormalizeURL(req.PrelaunchURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prelaunch_url: %v", err)
+		}
+		prelaunchURL = &normalized
+	}
+
+	var fallbackURL *string
+	if req.FallbackURL != "" {
+		normalized, err := validateAndNormalizeURL(req.FallbackURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback_url: %v", err)
+		}
+		fallbackURL = &normalized
+	}
+
+	forwardQuery := req.ForwardQuery
+	if forwardQuery == "" {
+		forwardQuery = models.QueryForwardNone
+	}
+	forwardQueryKeys, err := validateQueryForwarding(forwardQuery, req.ForwardQueryKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	pathMode := req.PathMode
+	if pathMode == "" {
+		pathMode = models.LinkPathExact
+	}
+	if !linkPathModes[pathMode] {
+		return nil, errors.New("path_mode must be one of exact, template or passthrough")
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := normalizeFolder(req.Folder)
+	if err != nil {
+		return nil, err
+	}
+	title, err := normalizeLinkText("title", req.Title, maxLinkTitleLength)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := normalizeLinkText("notes", req.Notes, maxLinkNotesLength)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := linkPath{Domain: domain, Namespace: namespace}.Scope()
+
 	var shortened string
 	if req.CustomURL != "" {
 		// Validate custom URL
@@ -153,7 +318,7 @@ func CreateLink(db *gorm.DB, req ShortenRequest, createdBy uuid.UUID) (*ShortenR
 			return nil, errors.New("custom_url must be alphanumeric")
 		}
 
-		exists, err := isShortenedURLTaken(db, req.CustomURL)
+		exists, err := isShortenedURLTaken(db, scope, req.CustomURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check custom URL: %v", err)
 		}
@@ -163,7 +328,7 @@ func CreateLink(db *gorm.DB, req ShortenRequest, createdBy uuid.UUID) (*ShortenR
 		shortened = req.CustomURL
 	} else {
 		// Generate a unique random URL
-		shortened, err = generateUniqueShortURL(db)
+		shortened, err = generateUniqueShortURL(db, scope)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate URL: %v", err)
 		}
@@ -171,18 +336,54 @@ func CreateLink(db *gorm.DB, req ShortenRequest, createdBy uuid.UUID) (*ShortenR
 
 	// Create the link record
 	link := models.Link{
-		RedirectTo: redirectURL,
-		Shortened:  shortened,
-		ExpiresAt:  req.ExpiresAt,
-		CreatedBy:  createdBy,
-		IsActive:   true,
+		RedirectTo:       redirectURL,
+		Shortened:        shortened,
+		ExpiresAt:        req.ExpiresAt,
+		CreatedBy:        createdBy,
+		IsActive:         true,
+		RedirectType:     req.RedirectType,
+		PasswordHash:     passwordHash,
+		MaxVisits:        req.MaxVisits,
+		StartsAt:         req.StartsAt,
+		PrelaunchURL:     prelaunchURL,
+		FallbackURL:      fallbackURL,
+		ForwardQuery:     forwardQuery,
+		ForwardQueryKeys: forwardQueryKeys,
+		PathMode:         pathMode,
+		DomainID:         scope.DomainID,
+		NamespaceID:      scope.NamespaceID,
+		Folder:           folder,
+		Title:            title,
+		Notes:            notes,
 	}
 
-	if err := db.Create(&link).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			linkTags, err := models.FindOrCreateTags(tx, tags)
+			if err != nil {
+				return err
+			}
+			if err := models.ReplaceLinkTags(tx, &link, linkTags); err != nil {
+				return err
+			}
+		}
+		return models.RecordInitialLinkRevision(tx, link)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("database error: %v", err)
 	}
 
-	return &ShortenResponse{ShortenedURL: shortened}, nil
+	response := &ShortenResponse{ShortenedURL: shortened}
+	if domain != nil {
+		response.Domain = domain.Host
+	}
+	if namespace != nil {
+		response.Namespace = namespace.Name
+	}
+	return response, nil
 }
 
 // validateAndNormalizeURL checks and normalizes the RedirectTo URL.
@@ -204,48 +405,73 @@ func validateAndNormalizeURL(rawURL string) (string, error) {
 		return "", fmt.Errorf("protocol %s is not allowed", u.Scheme)
 	}
 
-	// validate that the redirect URL is not the same as the public site URL
-	env := utils.LoadEnv()
-
-	if env.PUBLIC_SITE_URL != "" {
-		if u.Host == env.PUBLIC_SITE_URL {
-			return "", errors.New("cannot redirect to link shortener")
-		}
+	// validate that the redirect URL doesn't point back at the public site URL or one of the domains
+	if isShortenerHost(utils.LoadEnv(), u.Host) {
+		return "", errors.New("cannot redirect to link shortener")
 	}
 
 	return u.String(), nil
 }
 
+// isValidRedirectType checks if a link redirect type is empty (server default) or an allowed redirect status code.
+func isValidRedirectType(redirectType int) bool {
+	return redirectType == 0 || lib.REDIRECT_STATUS_CODES[redirectType]
+}
+
 // isAlphanumeric checks if a string contains only alphanumeric characters.
 func isAlphanumeric(s string) bool {
 	return regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString(s)
 }
 
-// isShortenedURLTaken checks if the given shortened URL already exists in the database or if it matches any reserved routes.
-func isShortenedURLTaken(db *gorm.DB, url string) (bool, error) {
-	// Check if the URL matches any reserved routes
-	if url == lib.RESERVED_ROUTES.API || url == lib.RESERVED_ROUTES.NotFound {
-		return true, nil // URL is a reserved route, so it's "taken"
-	}
+// isShortenedURLTaken checks if the given shortened URL already exists in a slug scope or if it matches any reserved routes.
+func isShortenedURLTaken(db *gorm.DB, scope models.SlugScope, url string) (bool, error) {
+	// Top-level slugs can't shadow reserved routes or namespaces, slugs inside a namespace are only prefixed by it
+	if scope.NamespaceID == nil {
+		if isReservedRoute(url) {
+			return true, nil // URL is a reserved route, so it's "taken"
+		}
 
-	if url == lib.RESERVED_ROUTES.Docs && utils.ENV.ENABLE_DOCS == "true" {
-		return true, nil // URL is a reserved route, so it's "taken"
+		_, err := models.FindNamespace(db, url)
+		if err == nil {
+			return true, nil // Found namespace
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err // Database error
+		}
 	}
 
-	// Proceed with the database check
+	// Proceed with the database check, trashed links still reserve their slug
 	var link models.Link
-	result := db.Where("shortened = ?", url).First(&link)
+	result := db.Unscoped().Scopes(scope.Apply).Where("shortened = ?", url).First(&link)
 	if result.Error == nil {
 		return true, nil // Found existing entry
 	}
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return false, result.Error // Database error
+	}
+
+	// Slugs of renamed links stay reserved as well
+	_, err := models.FindHistoricalSlug(db, scope, url)
+	if err == nil {
+		return true, nil // Found historical slug
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err // Database error
+	}
+
+	// Alias slugs resolve to their link, so they can't be reused either
+	_, err = models.FindLinkAlias(db, scope, url)
+	if err == nil {
+		return true, nil // Found alias
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return false, nil // No existing entry
 	}
-	return false, result.Error // Database error
+	return false, err // Database error
 }
 
 // generateUniqueShortURL generates a random alphanumeric URL and ensures it's unique.
-func generateUniqueShortURL(db *gorm.DB) (string, error) {
+func generateUniqueShortURL(db *gorm.DB, scope models.SlugScope) (string, error) {
 	const maxAttempts = 10
 	for i := 0; i < maxAttempts; i++ {
 		length, err := randomInt(3, 6)
@@ -259,7 +485,7 @@ func generateUniqueShortURL(db *gorm.DB) (string, error) {
 			continue
 		}
 
-		exists, err := isShortenedURLTaken(db, shortURL)
+		exists, err := isShortenedURLTaken(db, scope, shortURL)
 		if err != nil || exists {
 			continue
 		}
@@ -311,17 +537,37 @@ type PartialSecretKey struct {
 }
 
 type RetrieveLinkResponse struct {
-	ID            uuid.UUID        `json:"id"`
-	RedirectTo    string           `json:"redirect_to"`
-	Shortened     string           `json:"shortened"`
-	ExpiresAt     *time.Time       `json:"expires_at"`
-	CreatedAt     time.Time        `json:"created_at"`
-	UpdatedAt     time.Time        `json:"updated_at"`
-	CreatedBy     uuid.UUID        `json:"created_by"`
-	SecretKey     PartialSecretKey `json:"secret_key"`
-	Visits        int              `json:"visits"`
-	LastVisitedAt *time.Time       `json:"last_visited_at"`
-	IsActive      bool             `json:"is_active"`
+	ID               uuid.UUID                 `json:"id"`
+	RedirectTo       string                    `json:"redirect_to"`
+	Shortened        string                    `json:"shortened"`
+	ExpiresAt        *time.Time                `json:"expires_at"`
+	CreatedAt        time.Time                 `json:"created_at"`
+	UpdatedAt        time.Time                 `json:"updated_at"`
+	CreatedBy        uuid.UUID                 `json:"created_by"`
+	SecretKey        PartialSecretKey          `json:"secret_key"`
+	Visits           int                       `json:"visits"`
+	LastVisitedAt    *time.Time                `json:"last_visited_at"`
+	IsActive         bool                      `json:"is_active"`
+	RedirectType     int                       `json:"redirect_type"`
+	HasPassword      bool                      `json:"has_password"`
+	MaxVisits        *int                      `json:"max_visits"`
+	StartsAt         *time.Time                `json:"starts_at"`
+	PrelaunchURL     *string                   `json:"prelaunch_url"`
+	FallbackURL      *string                   `json:"fallback_url"`
+	DeletedAt        *time.Time                `json:"deleted_at,omitempty"`
+	Aliases          []string                  `json:"aliases"`
+	Rules            []LinkRuleResponse        `json:"rules"`
+	Variants         []LinkVariantResponse     `json:"variants"`
+	StickyVariants   bool                      `json:"sticky_variants"`
+	ForwardQuery     models.QueryForwardPolicy `json:"forward_query"`
+	ForwardQueryKeys []string                  `json:"forward_query_keys"`
+	PathMode         models.LinkPathMode       `json:"path_mode"`
+	Domain           *string                   `json:"domain"`
+	Namespace        *string                   `json:"namespace"`
+	Tags             []string                  `json:"tags"`
+	Folder           *string                   `json:"folder"`
+	Title            *string                   `json:"title"`
+	Notes            *string                   `json:"notes"`
 }
 
 // RetrieveLinkHandler retrieves details of a shortened link.
@@ -393,25 +639,82 @@ func ToSecretKeyResponse(s models.SecretKey) SecretKeyResponse {
 // convert models.Link to RetrieveLinkResponse
 func ToRetrieveLinkResponse(l models.Link) RetrieveLinkResponse {
 	return RetrieveLinkResponse{
-		ID:            l.ID,
-		RedirectTo:    l.RedirectTo,
-		Shortened:     l.Shortened,
-		ExpiresAt:     l.ExpiresAt,
-		CreatedAt:     l.CreatedAt,
-		UpdatedAt:     l.UpdatedAt,
-		CreatedBy:     l.CreatedBy,
-		SecretKey:     PartialSecretKey{Key: l.SecretKey.Key, Name: l.SecretKey.Name},
-		Visits:        l.Visits,
-		LastVisitedAt: l.LastVisitedAt,
-		IsActive:      l.IsActive,
+		ID:               l.ID,
+		RedirectTo:       l.RedirectTo,
+		Shortened:        l.Shortened,
+		ExpiresAt:        l.ExpiresAt,
+		CreatedAt:        l.CreatedAt,
+		UpdatedAt:        l.UpdatedAt,
+		CreatedBy:        l.CreatedBy,
+		SecretKey:        PartialSecretKey{Key: l.SecretKey.Key, Name: l.SecretKey.Name},
+		Visits:           l.Visits,
+		LastVisitedAt:    l.LastVisitedAt,
+		IsActive:         l.IsActive,
+		RedirectType:     l.RedirectType,
+		HasPassword:      l.PasswordHash != nil,
+		MaxVisits:        l.MaxVisits,
+		StartsAt:         l.StartsAt,
+		PrelaunchURL:     l.PrelaunchURL,
+		FallbackURL:      l.FallbackURL,
+		DeletedAt:        deletedAtPointer(l.DeletedAt),
+		Aliases:          aliasSlugs(l.Aliases),
+		Rules:            ToLinkRuleResponses(l.Rules),
+		Variants:         ToLinkVariantResponses(l.Variants),
+		StickyVariants:   l.StickyVariants,
+		ForwardQuery:     l.ForwardQuery,
+		ForwardQueryKeys: forwardQueryKeys(l),
+		PathMode:         l.PathMode,
+		Domain:           linkDomainHost(l),
+		Namespace:        linkNamespaceName(l),
+		Tags:             models.TagNames(l.Tags),
+		Folder:           l.Folder,
+		Title:            l.Title,
+		Notes:            l.Notes,
+	}
+}
+
+// linkNamespaceName returns the name of a link's namespace, or nil for top-level links
+func linkNamespaceName(link models.Link) *string {
+	if link.Namespace == nil {
+		return nil
 	}
+	return &link.Namespace.Name
 }
 
-// RetrieveLink, searches for a link by its shortened URL and returns the link object
+// aliasSlugs returns the slugs of a link's aliases
+func aliasSlugs(aliases []models.LinkAlias) []string {
+	slugs := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		slugs = append(slugs, alias.Slug)
+	}
+	return slugs
+}
+
+// deletedAtPointer returns the time a link was trashed, or nil if it isn't in the trash
+func deletedAtPointer(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Time
+}
+
+// RetrieveLink, searches for a link by its shortened URL and returns the link object.
+// Links on other domains than the default one are referred to as "host/shortened",
+// and links inside a namespace as "namespace/shortened" or "host/namespace/shortened".
 func RetrieveLink(db *gorm.DB, shortened string) (*models.Link, error) {
+	path, err := parseShortened(db, shortened)
+	if err != nil {
+		return nil, err
+	}
+	return retrieveScopedLink(db, path.Scope(), path.Slug)
+}
+
+// retrieveScopedLink searches a slug scope for a link by its slug
+func retrieveScopedLink(db *gorm.DB, scope models.SlugScope, slug string) (*models.Link, error) {
 	var link models.Link
 	// preload the SecretKey relationship
-	result := db.Preload("SecretKey").Where("shortened = ?", shortened).First(&link)
+	result := db.Preload("SecretKey").Preload("Aliases").Preload("Rules", models.OrderLinkRules).Preload("Variants", models.OrderLinkVariants).Preload("Domain").Preload("Namespace").Preload("Tags", models.OrderTags).
+		Scopes(scope.Apply).Where("shortened = ?", slug).First(&link)
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -420,9 +723,11 @@ func RetrieveLink(db *gorm.DB, shortened string) (*models.Link, error) {
 	return &link, nil
 }
 
-func RetrieveRedirectURL(db *gorm.DB, shortened string) (*models.Link, error) {
+// RetrieveRedirectURL returns the link with the given shortened URL in a slug scope.
+// Inactive links are returned as well so that visitors can be sent to their fallback.
+func RetrieveRedirectURL(db *gorm.DB, scope models.SlugScope, shortened string) (*models.Link, error) {
 	var link models.Link
-	result := db.Where("shortened = ? AND is_active = ?", shortened, true).First(&link)
+	result := db.Scopes(scope.Apply).Where("shortened = ?", shortened).First(&link)
 
 	if result.Error != nil {
 		return nil, result.Error
@@ -435,6 +740,93 @@ func RetrieveRedirectURL(db *gorm.DB, shortened string) (*models.Link, error) {
 	return &link, nil
 }
 
+// RetrieveRedirectURLByID returns the link with the given ID, active or not
+func RetrieveRedirectURLByID(db *gorm.DB, linkID uuid.UUID) (*models.Link, error) {
+	var link models.Link
+	result := db.Where("id = ?", linkID).First(&link)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if link.RedirectTo == "" {
+		return nil, errors.New("invalid redirect")
+	}
+
+	return &link, nil
+}
+
+// canManageLink reports whether the requesting key may modify the given link.
+// Admins may manage every link, namespace owners the links inside their namespace, everyone else only the links they created.
+func canManageLink(ctxValues ContextValues, link models.Link) bool {
+	if link.Namespace != nil && ctxValues.KeyID == link.Namespace.OwnerID {
+		return true
+	}
+	return ctxValues.IsAdmin || ctxValues.KeyID == link.CreatedBy
+}
+
+// retrieveManageableLink loads a link, or a trashed link if fromTrash is set, and verifies that the requesting key may manage it.
+// It writes the error response itself and returns nil if the link can't be used.
+func retrieveManageableLink(w http.ResponseWriter, r *http.Request, ctxValues ContextValues, shortened string, fromTrash bool) *models.Link {
+	db := database.GetDB()
+	if db == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   lib.ERRORS.Database,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return nil
+	}
+
+	var link *models.Link
+	var err error
+	if fromTrash {
+		var path linkPath
+		if path, err = parseShortened(db, shortened); err == nil {
+			link, err = models.RetrieveTrashedLink(db, path.Scope(), path.Slug)
+		}
+	} else {
+		link, err = RetrieveLink(db, shortened)
+	}
+	if err != nil {
+		message := "Link not found"
+		if fromTrash {
+			message = "Link not found in trash"
+		}
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Shortened URL: %s", shortened),
+		}
+		writeErrorResponse(w, config)
+		return nil
+	}
+
+	if !canManageLink(ctxValues, *link) {
+		config := ErrorResponseConfig{
+			Status:    http.StatusUnauthorized,
+			Message:   "Unauthorized to manage this link",
+			LogType:   models.LogTypeWarning,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("User ID: %s, Link Creator: %s", ctxValues.KeyID, link.CreatedBy),
+		}
+		writeErrorResponse(w, config)
+		return nil
+	}
+
+	return link
+}
+
 type DeleteLinkRequest struct {
 	Shortened string `json:"shortened"`
 }
@@ -443,9 +835,9 @@ type DeleteLinkResponse struct {
 	Message string `json:"message"`
 }
 
-// DeleteLinkHandler deletes a shortened link.
+// DeleteLinkHandler moves a shortened link to the trash.
 // @Summary Delete a shortened link
-// @Description Deletes a shortened link by its shortened URL.
+// @Description Moves a shortened link to the trash by its shortened URL. Trashed links stop redirecting, but keep their slug and visits until they are restored or purged.
 // @Tags links
 // @Accept json
 // @Produce json
@@ -513,7 +905,7 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if secretKey.ID != link.CreatedBy {
+		if !canManageLink(ctxValues, *link) {
 			config := ErrorResponseConfig{
 				Status:    http.StatusUnauthorized,
 				Message:   "Unauthorized to delete this link",
@@ -528,7 +920,9 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// delete link
+	linkBefore := models.LinkAuditSnapshot(*link)
+
+	// move link to the trash
 	if err := db.Delete(&link).Error; err != nil {
 		config := ErrorResponseConfig{
 			Status:    http.StatusInternalServerError,
@@ -547,16 +941,51 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Link deleted successfully",
+		"message": "Link moved to trash successfully",
 	})
+
+	recordAuditEvent(r, ctxValues, models.AuditActionLinkDelete, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, linkBefore, nil)
 }
 
+// redirect_type: The HTTP status code to redirect with (301, 302, 307 or 308), 0 resets it to the server default
+// keep_old_slug: When renaming with new_shortened, keep the old slug as a historical alias that still redirects (default: true)
+// old_slug_redirects_to_new: Make the historical alias redirect to the new slug instead of straight to the destination
+// password: Sets the password visitors must enter before being redirected, an empty string removes it
+// max_visits: Sets the number of visits after which the link is deactivated, 0 removes the limit
+// starts_at: Sets the time the link goes live, 1970-01-01T00:00:00Z makes it live immediately
+// prelaunch_url: Sets the URL visitors are sent to before starts_at, an empty string shows the "coming soon" page instead
+// fallback_url: Sets the URL visitors are sent to once the link expires or is deactivated, an empty string removes it
+// sticky_variants: Sends returning visitors to the variant they saw first, using a visitor cookie
+// forward_query: Sets how the query string of a visit is merged into redirect_to: none, incoming, destination or allowlist
+// forward_query_keys: Sets the query keys forwarded by the allowlist policy
+// path_mode: Sets what happens to path segments after the slug: exact, template or passthrough
+// tags: Replaces the tags of the link, an empty list removes every tag
+// folder: Moves the link to another folder, an empty string takes it out of its folder
+// title: Sets the title of the link, an empty string removes it
+// notes: Sets the notes of the link, an empty string removes them
 type UpdateLinkRequest struct {
-	Shortened    string     `json:"shortened"`
-	RedirectTo   *string    `json:"redirect_to,omitempty"`
-	NewShortened *string    `json:"new_shortened,omitempty"`
-	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
-	IsActive     *bool      `json:"is_active,omitempty"`
+	Shortened             string                     `json:"shortened"`
+	RedirectTo            *string                    `json:"redirect_to,omitempty"`
+	NewShortened          *string                    `json:"new_shortened,omitempty"`
+	ExpiresAt             *time.Time                 `json:"expires_at,omitempty"`
+	IsActive              *bool                      `json:"is_active,omitempty"`
+	KeepOldSlug           *bool                      `json:"keep_old_slug,omitempty"`
+	OldSlugRedirectsToNew bool                       `json:"old_slug_redirects_to_new,omitempty"`
+	RedirectType          *int                       `json:"redirect_type,omitempty"`
+	Password              *string                    `json:"password,omitempty"`
+	MaxVisits             *int                       `json:"max_visits,omitempty"`
+	StartsAt              *time.Time                 `json:"starts_at,omitempty"`
+	PrelaunchURL          *string                    `json:"prelaunch_url,omitempty"`
+	FallbackURL           *string                    `json:"fallback_url,omitempty"`
+	StickyVariants        *bool                      `json:"sticky_variants,omitempty"`
+	ForwardQuery          *models.QueryForwardPolicy `json:"forward_query,omitempty"`
+	ForwardQueryKeys      []string                   `json:"forward_query_keys,omitempty"`
+	PathMode              *models.LinkPathMode       `json:"path_mode,omitempty"`
+	Tags                  []string                   `json:"tags,omitempty"`
+	Folder                *string                    `json:"folder,omitempty"`
+	Title                 *string                    `json:"title,omitempty"`
+	Notes                 *string                    `json:"notes,omitempty"`
 }
 
 type UpdateLinkResponse struct {
@@ -575,6 +1004,7 @@ type UpdateLinkResponse struct {
 // UpdateLinkHandler updates a shortened link.
 // @Summary Update a shortened link
 // @Description Updates a shortened link with new values for redirect URL, shortened URL, expiration date, or active status.
+// @Description When the shortened URL is renamed, the old one is kept as a historical slug that keeps redirecting, unless keep_old_slug is false.
 // @Tags links
 // @Accept json
 // @Produce json
@@ -627,7 +1057,7 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 	// auth check
 	if !ctxValues.IsAdmin {
 		secretKey := models.SearchKeyByKey(db, ctxValues.SecretKey)
-		if secretKey == nil || secretKey.ID != link.CreatedBy {
+		if secretKey == nil || !canManageLink(ctxValues, *link) {
 			config := ErrorResponseConfig{
 				Status:    http.StatusUnauthorized,
 				Message:   "Unauthorized to update this link",
@@ -641,6 +1071,9 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	previous := *link
+	linkBefore := models.LinkAuditSnapshot(previous)
+
 	// validate and apply updates
 	if request.RedirectTo != nil {
 		normalized, err := validateAndNormalizeURL(*request.RedirectTo)
@@ -659,6 +1092,10 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 		link.RedirectTo = normalized
 	}
 
+	// slug the link is renamed from, and whether the new slug is one of the link's own historical slugs
+	var renamedFrom string
+	var reclaimsHistoricalSlug bool
+
 	if request.NewShortened != nil {
 		newShort := *request.NewShortened
 		if newShort != link.Shortened {
@@ -675,7 +1112,12 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			exists, err := isShortenedURLTaken(db, newShort)
+			// a link may always take back one of its own historical slugs
+			if historicalSlug, err := models.FindHistoricalSlug(db, models.LinkSlugScope(*link), newShort); err == nil && historicalSlug.LinkID == link.ID {
+				reclaimsHistoricalSlug = true
+			}
+
+			exists, err := isShortenedURLTaken(db, models.LinkSlugScope(*link), newShort)
 			if err != nil {
 				config := ErrorResponseConfig{
 					Status:    http.StatusInternalServerError,
@@ -688,7 +1130,7 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 				writeErrorResponse(w, config)
 				return
 			}
-			if exists {
+			if exists && !reclaimsHistoricalSlug {
 				config := ErrorResponseConfig{
 					Status:    http.StatusConflict,
 					Message:   "New shortened URL already exists",
@@ -700,6 +1142,7 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 				writeErrorResponse(w, config)
 				return
 			}
+			renamedFrom = link.Shortened
 			link.Shortened = newShort
 		}
 	}
@@ -720,12 +1163,228 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if request.StartsAt != nil {
+		// the earliest possible timestamp unsets it, just like expires_at
+		if request.StartsAt.Equal(time.Unix(0, 0)) {
+			link.StartsAt = nil
+		} else {
+			link.StartsAt = request.StartsAt
+		}
+	}
+
+	if request.PrelaunchURL != nil {
+		if *request.PrelaunchURL == "" {
+			link.PrelaunchURL = nil
+		} else {
+			normalized, err := validateAndNormalizeURL(*request.PrelaunchURL)
+			if err != nil {
+				config := ErrorResponseConfig{
+					Status:    http.StatusBadRequest,
+					Message:   fmt.Sprintf("invalid prelaunch_url: %v", err),
+					LogType:   models.LogTypeError,
+					LogSource: models.LogSourceLinks,
+					Request:   r,
+					CtxValues: &ctxValues,
+				}
+				writeErrorResponse(w, config)
+				return
+			}
+			link.PrelaunchURL = &normalized
+		}
+	}
+
+	if request.FallbackURL != nil {
+		if *request.FallbackURL == "" {
+			link.FallbackURL = nil
+		} else {
+			normalized, err := validateAndNormalizeURL(*request.FallbackURL)
+			if err != nil {
+				config := ErrorResponseConfig{
+					Status:    http.StatusBadRequest,
+					Message:   fmt.Sprintf("invalid fallback_url: %v", err),
+					LogType:   models.LogTypeError,
+					LogSource: models.LogSourceLinks,
+					Request:   r,
+					CtxValues: &ctxValues,
+				}
+				writeErrorResponse(w, config)
+				return
+			}
+			link.FallbackURL = &normalized
+		}
+	}
+
+	if link.StartsAt != nil && link.ExpiresAt != nil && !link.StartsAt.Before(*link.ExpiresAt) {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "starts_at must be before expires_at",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
 	if request.IsActive != nil {
 		link.IsActive = *request.IsActive
 	}
 
-	// Save updates
-	if err := db.Save(&link).Error; err != nil {
+	if request.StickyVariants != nil {
+		link.StickyVariants = *request.StickyVariants
+	}
+
+	if request.ForwardQuery != nil || request.ForwardQueryKeys != nil {
+		policy := link.ForwardQuery
+		if request.ForwardQuery != nil {
+			policy = *request.ForwardQuery
+		}
+		keys := forwardQueryKeys(*link)
+		if request.ForwardQueryKeys != nil {
+			keys = request.ForwardQueryKeys
+		}
+		storedKeys, err := validateQueryForwarding(policy, keys)
+		if err != nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		link.ForwardQuery = policy
+		link.ForwardQueryKeys = storedKeys
+	}
+
+	if request.PathMode != nil {
+		if !linkPathModes[*request.PathMode] {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   "path_mode must be one of exact, template or passthrough",
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		link.PathMode = *request.PathMode
+	}
+
+	if request.RedirectType != nil {
+		if !isValidRedirectType(*request.RedirectType) {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   "redirect_type must be one of 301, 302, 307 or 308",
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		link.RedirectType = *request.RedirectType
+	}
+
+	if request.Password != nil {
+		passwordHash, err := hashLinkPassword(*request.Password)
+		if err != nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		link.PasswordHash = passwordHash
+	}
+
+	if request.MaxVisits != nil {
+		if *request.MaxVisits < 0 {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   "max_visits must not be negative",
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		if *request.MaxVisits == 0 {
+			link.MaxVisits = nil
+		} else {
+			link.MaxVisits = request.MaxVisits
+		}
+	}
+
+	var tags []string
+	if request.Tags != nil {
+		tags, err = normalizeTags(request.Tags)
+	}
+	if err == nil && request.Folder != nil {
+		link.Folder, err = normalizeFolder(*request.Folder)
+	}
+	if err == nil && request.Title != nil {
+		link.Title, err = normalizeLinkText("title", *request.Title, maxLinkTitleLength)
+	}
+	if err == nil && request.Notes != nil {
+		link.Notes, err = normalizeLinkText("notes", *request.Notes, maxLinkNotesLength)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	keepOldSlug := request.KeepOldSlug == nil || *request.KeepOldSlug
+
+	// Save updates along with a revision of the changed destination, expiry or active flag
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if reclaimsHistoricalSlug {
+			if _, err := models.ReleaseHistoricalSlug(tx, link.ID, link.Shortened); err != nil {
+				return err
+			}
+		}
+		if err := models.SaveLinkChanges(tx, previous, link); err != nil {
+			return err
+		}
+		if request.Tags != nil {
+			linkTags, err := models.FindOrCreateTags(tx, tags)
+			if err != nil {
+				return err
+			}
+			if err := models.ReplaceLinkTags(tx, link, linkTags); err != nil {
+				return err
+			}
+		}
+		if renamedFrom != "" && keepOldSlug {
+			if err := models.RecordHistoricalSlug(tx, link.ID, models.LinkSlugScope(*link), renamedFrom, request.OldSlugRedirectsToNew); err != nil {
+				return err
+			}
+		}
+		return models.RecordLinkRevision(tx, previous, *link, ctxValues.KeyID)
+	})
+	if err != nil {
 		config := ErrorResponseConfig{
 			Status:    http.StatusInternalServerError,
 			Message:   "Failed to update link",
@@ -744,20 +1403,36 @@ func UpdateLinkHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
+
+	recordAuditEvent(r, ctxValues, models.AuditActionLinkUpdate, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, linkBefore, models.LinkAuditSnapshot(*link))
 }
 
 type RetrieveAllLinksResponse struct {
 	Message string                 `json:"message"`
 	Links   []RetrieveLinkResponse `json:"links"`
+	models.PageInfo
 }
 
 // RetrieveAllLinksHandler retrieves all shortened links.
 // @Summary Retrieve all shortened links
-// @Description Retrieves all shortened links from the database.
+// @Description Retrieves a page of the shortened links from the database, newest first by default. Pass next_cursor as cursor to fetch the next page.
 // @Tags links,admin
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param cursor query string false "The next_cursor of the previous page"
+// @Param sort query string false "created_at (default), visits or last_visited_at"
+// @Param order query string false "asc or desc (default)"
+// @Param status query string false "Only retrieve links that are active, inactive or expired"
+// @Param created_from query string false "Only retrieve links created at or after this RFC 3339 time"
+// @Param created_to query string false "Only retrieve links created at or before this RFC 3339 time"
+// @Param expires_from query string false "Only retrieve links expiring at or after this RFC 3339 time"
+// @Param expires_to query string false "Only retrieve links expiring at or before this RFC 3339 time"
+// @Param destination_domain query string false "Only retrieve links redirecting to this host or its subdomains"
+// @Param tag query string false "Only retrieve links with this tag"
+// @Param folder query string false "Only retrieve links in this folder or its subfolders"
 // @Success 200 {object} RetrieveAllLinksResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
@@ -801,18 +1476,49 @@ func RetrieveAllLinksHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Retrieve all links from the database
-	links := models.RetrieveAllLinks(db)
+	listRequest, err := listRequestFromQuery(r.URL.Query())
+	var filterRequest LinkFilterRequest
+	if err == nil {
+		filterRequest, err = linkFilterFromQuery(r.URL.Query())
+	}
+	var filter models.LinkFilter
+	if err == nil {
+		filter, err = filterRequest.filter()
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
 
-	// Convert each link to RetrieveLinkResponse
-	var responseLinks []RetrieveLinkResponse
-	for _, link := range links {
-		responseLinks = append(responseLinks, ToRetrieveLinkResponse(link))
+	// Retrieve a page of links from the database
+	links, page, err := models.RetrieveAllLinks(db, filter, listRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
 	}
 
 	response := RetrieveAllLinksResponse{
-		Message: "Links retrieved successfully",
-		Links:   responseLinks,
+		Message:  "Links retrieved successfully",
+		Links:    ToRetrieveLinkResponses(links),
+		PageInfo: page,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -834,18 +1540,22 @@ func RetrieveAllLinksHandler(w http.ResponseWriter, r *http.Request) {
 		"Retrieved all links. Requested by: '"+ctxValues.SecretKey+"'", r.RemoteAddr)
 }
 
+// sort: created_at (default), visits or last_visited_at
 type RetrieveAllLinksByKeyRequest struct {
 	Key string `json:"key"`
+	ListRequest
+	LinkFilterRequest
 }
 
 type RetrieveAllLinksByKeyResponse struct {
 	Message string                 `json:"message"`
 	Links   []RetrieveLinkResponse `json:"links"`
+	models.PageInfo
 }
 
 // RetrieveAllLinksByKeyHandler retrieves all shortened links by a secret key.
 // @Summary Retrieve all shortened links by a secret key
-// @Description Retrieves all shortened links by a secret key from the database.
+// @Description Retrieves a page of the shortened links created by a secret key, newest first by default. Pass next_cursor as cursor to fetch the next page.
 // @Tags links
 // @Accept json
 // @Produce json
@@ -927,12 +1637,27 @@ func RetrieveAllLinksByKeyHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// retrieve all links by the secret key
-	links, err := models.RetrieveAllLinksByKey(db, request.Key)
+	filter, err := request.LinkFilterRequest.filter()
 	if err != nil {
 		config := ErrorResponseConfig{
-			Status:    http.StatusInternalServerError,
-			Message:   "Database Error",
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	// retrieve a page of links by the secret key
+	links, page, err := models.RetrieveAllLinksByKey(db, request.Key, filter, request.ListRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
 			LogType:   models.LogTypeError,
 			LogSource: models.LogSourceLinks,
 			Request:   r,
@@ -945,8 +1670,9 @@ func RetrieveAllLinksByKeyHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Convert to response struct
 	response := RetrieveAllLinksByKeyResponse{
-		Message: "Links retrieved successfully",
-		Links:   ToRetrieveLinkResponses(links),
+		Message:  "Links retrieved successfully",
+		Links:    ToRetrieveLinkResponses(links),
+		PageInfo: page,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -75,6 +75,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		// Attach the key object to the request context
 		ctxValues := ContextValues{
 			SecretKey: keyObj.Key,
+			KeyID:     keyObj.ID,
 			IsAdmin:   keyObj.IsAdmin,
 		}
 		ctx := context.WithValue(r.Context(), secretKeyContextKey, ctxValues)
@@ -0,0 +1,615 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// linkPath is a reference to a link slug, with the domain and namespace it lives in
+type linkPath struct {
+	Domain    *models.Domain
+	Namespace *models.Namespace
+	Slug      string
+	// Rest is the escaped path after the slug, which template and passthrough links build the destination from
+	Rest string
+}
+
+// Scope returns the slug scope the path is resolved in
+func (p linkPath) Scope() models.SlugScope {
+	scope := models.SlugScope{DomainID: domainID(p.Domain)}
+	if p.Namespace != nil {
+		scope.NamespaceID = &p.Namespace.ID
+	}
+	return scope
+}
+
+// Display returns the slug as visitors see it, prefixed with its namespace, e.g. "eng/docs"
+func (p linkPath) Display() string {
+	return namespacedSlug(p.Namespace, p.Slug)
+}
+
+// namespacedSlug prefixes a slug with its namespace, if it has one
+func namespacedSlug(namespace *models.Namespace, slug string) string {
+	if namespace == nil {
+		return slug
+	}
+	return namespace.Name + "/" + slug
+}
+
+// linkReference returns how the API refers to a link, e.g. "docs", "eng/docs" or "go.example.com/eng/docs", see parseShortened
+func linkReference(link models.Link) string {
+	reference := namespacedSlug(link.Namespace, link.Shortened)
+	if link.Domain != nil {
+		reference = link.Domain.Host + "/" + reference
+	}
+	return reference
+}
+
+// parseRequestPath resolves an escaped request path, without its leading slash, on the domain the request was sent to.
+// The first segment is the slug, unless it names a namespace, then the second segment is the slug inside it.
+func parseRequestPath(db *gorm.DB, r *http.Request, escapedPath string) (linkPath, error) {
+	var path linkPath
+	var err error
+	if path.Domain, err = requestDomain(db, r); err != nil {
+		return path, err
+	}
+
+	rawSlug, rest, _ := strings.Cut(escapedPath, "/")
+	if rest != "" {
+		namespace, err := models.FindNamespace(db, rawSlug)
+		if err == nil {
+			path.Namespace = namespace
+			rawSlug, rest, _ = strings.Cut(rest, "/")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return path, err
+		}
+	}
+
+	if path.Slug, err = url.PathUnescape(rawSlug); err != nil {
+		path.Slug = rawSlug
+	}
+	path.Rest = strings.TrimRight(rest, "/")
+	return path, nil
+}
+
+// parseShortened resolves how management requests refer to links: "slug", "namespace/slug", "host/slug"
+// or "host/namespace/slug", where a bare slug is on the default domain and outside any namespace
+func parseShortened(db *gorm.DB, shortened string) (linkPath, error) {
+	var path linkPath
+	parts := strings.Split(shortened, "/")
+
+	switch len(parts) {
+	case 1:
+	case 2:
+		// a registered host comes first, otherwise it's a namespace on the default domain
+		domain, err := models.FindDomainByHost(db, parts[0])
+		if err == nil {
+			path.Domain = domain
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			if path.Namespace, err = models.FindNamespace(db, parts[0]); err != nil {
+				return path, err
+			}
+		} else {
+			return path, err
+		}
+	case 3:
+		var err error
+		if path.Domain, err = models.FindDomainByHost(db, parts[0]); err != nil {
+			return path, err
+		}
+		if path.Namespace, err = models.FindNamespace(db, parts[1]); err != nil {
+			return path, err
+		}
+	default:
+		return path, gorm.ErrRecordNotFound
+	}
+
+	path.Slug = parts[len(parts)-1]
+	return path, nil
+}
+
+// isReservedRoute reports whether a top-level slug is used by the app's own routes
+func isReservedRoute(slug string) bool {
+	if slug == lib.RESERVED_ROUTES.API || slug == lib.RESERVED_ROUTES.NotFound || slug == lib.RESERVED_ROUTES.Preview {
+		return true
+	}
+	return slug == lib.RESERVED_ROUTES.Docs && utils.ENV.ENABLE_DOCS == "true"
+}
+
+// canManageNamespace reports whether a key may change a namespace: its owner and admins may
+func canManageNamespace(ctxValues ContextValues, namespace models.Namespace) bool {
+	return ctxValues.IsAdmin || ctxValues.KeyID == namespace.OwnerID
+}
+
+// KeyReference names a key without revealing its secret
+type KeyReference struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// toKeyReferences converts keys to KeyReferences
+func toKeyReferences(keys []models.SecretKey) []KeyReference {
+	references := make([]KeyReference, 0, len(keys))
+	for _, key := range keys {
+		references = append(references, KeyReference{ID: key.ID, Name: key.Name})
+	}
+	return references
+}
+
+type NamespaceResponse struct {
+	ID        uuid.UUID      `json:"id"`
+	Name      string         `json:"name"`
+	Owner     KeyReference   `json:"owner"`
+	Members   []KeyReference `json:"members"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// convert models.Namespace to NamespaceResponse
+func ToNamespaceResponse(n models.Namespace) NamespaceResponse {
+	return NamespaceResponse{
+		ID:        n.ID,
+		Name:      n.Name,
+		Owner:     KeyReference{ID: n.OwnerID, Name: n.Owner.Name},
+		Members:   toKeyReferences(n.Members),
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+	}
+}
+
+// namespaceAuditSnapshot returns the audited fields of a namespace
+func namespaceAuditSnapshot(n models.Namespace) map[string]interface{} {
+	members := make([]string, 0, len(n.Members))
+	for _, member := range n.Members {
+		members = append(members, member.ID.String())
+	}
+	return map[string]interface{}{
+		"name":     n.Name,
+		"owner_id": n.OwnerID.String(),
+		"members":  members,
+	}
+}
+
+type RetrieveNamespacesResponse struct {
+	Message    string              `json:"message"`
+	Namespaces []NamespaceResponse `json:"namespaces"`
+	models.PageInfo
+}
+
+// RetrieveNamespacesHandler retrieves the namespaces visible to the requesting key.
+// @Summary Retrieve namespaces
+// @Description Retrieves the namespaces the requesting key owns or is a member of. Admins retrieve every namespace.
+// @Tags namespaces
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param cursor query string false "The next_cursor of the previous page"
+// @Param sort query string false "name (default) or created_at"
+// @Param order query string false "asc or desc, the default is asc for name and desc for created_at"
+// @Success 200 {object} RetrieveNamespacesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/namespaces/retrieve-all [get]
+func RetrieveNamespacesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	var keyID *uuid.UUID
+	if !ctxValues.IsAdmin {
+		keyID = &ctxValues.KeyID
+	}
+
+	listRequest, err := listRequestFromQuery(r.URL.Query())
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	namespaces, page, err := models.RetrieveNamespaces(database.GetDB(), keyID, listRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	responses := make([]NamespaceResponse, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		responses = append(responses, ToNamespaceResponse(namespace))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetrieveNamespacesResponse{
+		Message:    "Namespaces retrieved successfully",
+		Namespaces: responses,
+		PageInfo:   page,
+	})
+}
+
+// name: The namespace prefix, e.g. "eng" for links at "/eng/{slug}". It must be alphanumeric and unused as a top-level slug
+// owner: The ID or name of the key that owns the namespace, only admins may set it. If empty, the requesting key owns it
+// members: The IDs or names of the keys that may create links inside the namespace besides the owner
+type CreateNamespaceRequest struct {
+	Name    string   `json:"name"`
+	Owner   string   `json:"owner,omitempty"`
+	Members []string `json:"members,omitempty"`
+}
+
+// CreateNamespaceHandler creates a slug namespace owned by a key.
+// @Summary Create a namespace
+// @Description Reserves a top-level slug as a namespace. Links inside it are reached at /{namespace}/{slug} on every domain, and only its owner and members may create them.
+// @Tags namespaces
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body CreateNamespaceRequest true "Namespace creation request"
+// @Success 200 {object} NamespaceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/namespaces/create [post]
+func CreateNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request CreateNamespaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	db := database.GetDB()
+
+	namespace := models.Namespace{Name: request.Name, OwnerID: ctxValues.KeyID}
+
+	var err error
+	if len(request.Name) > 100 || !isAlphanumeric(request.Name) {
+		err = errors.New("name must be alphanumeric and at most 100 characters long")
+	} else if request.Owner != "" {
+		if !ctxValues.IsAdmin {
+			err = errors.New("only admins may create namespaces for other keys")
+		} else if owner := resolveKey(db, request.Owner); owner == nil {
+			err = errors.New("owner key not found")
+		} else {
+			namespace.OwnerID = owner.ID
+		}
+	}
+	var members []models.SecretKey
+	if err == nil {
+		members, err = resolveKeys(db, request.Members)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	// the name becomes a top-level path prefix, so it can't shadow routes, slugs or other namespaces
+	taken := isReservedRoute(namespace.Name)
+	if !taken {
+		if _, err = models.FindNamespace(db, namespace.Name); err == nil {
+			taken = true
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			taken, err = models.IsTopLevelSlugUsed(db, namespace.Name)
+		}
+	}
+	if err == nil && taken {
+		config := ErrorResponseConfig{
+			Status:    http.StatusConflict,
+			Message:   "name is already in use, or is reserved",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if err == nil {
+		err = models.SaveNamespace(db, &namespace, members)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to create namespace",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	created, err := models.FindNamespace(db, namespace.Name)
+	if err == nil {
+		namespace = *created
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToNamespaceResponse(namespace))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionNamespaceCreate, models.AuditTargetNamespace,
+		namespace.ID.String(), namespace.Name, nil, namespaceAuditSnapshot(namespace))
+}
+
+// name: The namespace to update
+// owner: Hands the namespace over to another key, given by its ID or name
+// members: Replaces the members of the namespace with the keys of these IDs or names, an empty list removes every member
+type UpdateNamespaceRequest struct {
+	Name    string    `json:"name"`
+	Owner   *string   `json:"owner,omitempty"`
+	Members *[]string `json:"members,omitempty"`
+}
+
+// UpdateNamespaceHandler changes the owner or members of a namespace.
+// @Summary Update a namespace
+// @Description Changes who owns a namespace or who may create links inside it. Only its owner and admins may update it.
+// @Tags namespaces
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body UpdateNamespaceRequest true "Namespace update request"
+// @Success 200 {object} NamespaceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/namespaces/update [post]
+func UpdateNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateNamespaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	db := database.GetDB()
+
+	namespace := retrieveManageableNamespace(w, r, ctxValues, db, request.Name)
+	if namespace == nil {
+		return
+	}
+	namespaceBefore := namespaceAuditSnapshot(*namespace)
+
+	var err error
+	members := namespace.Members
+	if request.Members != nil {
+		members, err = resolveKeys(db, *request.Members)
+	}
+	if err == nil && request.Owner != nil {
+		if owner := resolveKey(db, *request.Owner); owner == nil {
+			err = errors.New("owner key not found")
+		} else {
+			namespace.OwnerID = owner.ID
+			namespace.Owner = *owner
+		}
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if err := models.SaveNamespace(db, namespace, members); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to update namespace",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToNamespaceResponse(*namespace))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionNamespaceUpdate, models.AuditTargetNamespace,
+		namespace.ID.String(), namespace.Name, namespaceBefore, namespaceAuditSnapshot(*namespace))
+}
+
+type DeleteNamespaceRequest struct {
+	Name string `json:"name"`
+}
+
+type DeleteNamespaceResponse struct {
+	Message string `json:"message"`
+}
+
+// DeleteNamespaceHandler deletes a namespace that has no links.
+// @Summary Delete a namespace
+// @Description Deletes a namespace, freeing its name. Namespaces that still have links, including trashed ones, can't be deleted. Only its owner and admins may delete it.
+// @Tags namespaces
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body DeleteNamespaceRequest true "Namespace deletion request"
+// @Success 200 {object} DeleteNamespaceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/namespaces/delete [post]
+func DeleteNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request DeleteNamespaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	db := database.GetDB()
+
+	namespace := retrieveManageableNamespace(w, r, ctxValues, db, request.Name)
+	if namespace == nil {
+		return
+	}
+
+	count, err := models.CountNamespaceLinks(db, namespace.ID)
+	if err == nil && count > 0 {
+		config := ErrorResponseConfig{
+			Status:    http.StatusConflict,
+			Message:   fmt.Sprintf("The namespace still has %d links, purge them first", count),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if err == nil {
+		err = models.DeleteNamespace(db, namespace)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to delete namespace",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeleteNamespaceResponse{Message: "Namespace deleted successfully"})
+
+	recordAuditEvent(r, ctxValues, models.AuditActionNamespaceDelete, models.AuditTargetNamespace,
+		namespace.ID.String(), namespace.Name, namespaceAuditSnapshot(*namespace), nil)
+}
+
+// retrieveManageableNamespace loads a namespace and verifies that the requesting key may manage it.
+// It writes the error response itself and returns nil if the namespace can't be used.
+func retrieveManageableNamespace(w http.ResponseWriter, r *http.Request, ctxValues ContextValues, db *gorm.DB, name string) *models.Namespace {
+	namespace, err := models.FindNamespace(db, name)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Namespace not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Namespace: %s, Error: %v", name, err),
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			config.Status = http.StatusInternalServerError
+			config.Message = lib.ERRORS.Database
+		}
+		writeErrorResponse(w, config)
+		return nil
+	}
+
+	if !canManageNamespace(ctxValues, *namespace) {
+		config := ErrorResponseConfig{
+			Status:    http.StatusUnauthorized,
+			Message:   "Unauthorized to manage this namespace",
+			LogType:   models.LogTypeWarning,
+			LogSource: models.LogSourceNamespaces,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("User ID: %s, Namespace Owner: %s", ctxValues.KeyID, namespace.OwnerID),
+		}
+		writeErrorResponse(w, config)
+		return nil
+	}
+
+	return namespace
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page identifies an HTML page served by the redirect router instead of a redirect
+type Page string
+
+const (
+	PageNotFound         Page = "not_found"
+	PageExpired          Page = "expired"
+	PageDisabled         Page = "disabled"
+	PagePasswordRequired Page = "password_required"
+	PageRateLimited      Page = "rate_limited"
+	PageComingSoon       Page = "coming_soon"
+	PagePreview          Page = "preview"
+	PageError            Page = "error"
+)
+
+// pageTitles are the headings of the pages, also used as the JSON message
+var pageTitles = map[Page]string{
+	PageNotFound:         "Link not found",
+	PageExpired:          "Link expired",
+	PageDisabled:         "Link disabled",
+	PagePasswordRequired: "Password required",
+	PageRateLimited:      "Too many attempts",
+	PageComingSoon:       "Coming soon",
+	PagePreview:          "Link preview",
+	PageError:            "Something went wrong",
+}
+
+// the default page templates, operators can override any of them (including layout.html) in PAGE_TEMPLATES_DIR
+//
+//go:embed templates/*.html
+var defaultPageTemplates embed.FS
+
+var pageTemplates = mustLoadPageTemplates("")
+
+// PageData is passed to the page templates
+type PageData struct {
+	Title    string
+	Status   int
+	Slug     string
+	Error    string
+	StartsAt *time.Time
+	Preview  *LinkPreviewResponse
+}
+
+// RedirectErrorResponse is sent instead of a page to clients that prefer JSON
+type RedirectErrorResponse struct {
+	Message  string     `json:"message"`
+	Code     Page       `json:"code"`
+	Slug     string     `json:"slug,omitempty"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+}
+
+// mustLoadPageTemplates loads the page templates, panicking if the embedded defaults are broken
+func mustLoadPageTemplates(overrideDir string) map[Page]*template.Template {
+	templates, err := loadPageTemplates(overrideDir)
+	if err != nil {
+		log.Panicf("Error loading page templates: %v", err)
+	}
+	return templates
+}
+
+// loadPageTemplates parses the layout and content template of every page.
+// Files in overrideDir replace the embedded default with the same name.
+func loadPageTemplates(overrideDir string) (map[Page]*template.Template, error) {
+	layout, err := readPageTemplate(overrideDir, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[Page]*template.Template, len(pageTitles))
+	for page := range pageTitles {
+		content, err := readPageTemplate(overrideDir, string(page)+".html")
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := template.New(string(page)).Parse(layout)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.Parse(content); err != nil {
+			return nil, err
+		}
+		templates[page] = tmpl
+	}
+
+	return templates, nil
+}
+
+// readPageTemplate reads a template file from the override directory, falling back to the embedded default
+func readPageTemplate(overrideDir string, name string) (string, error) {
+	if overrideDir != "" {
+		content, err := os.ReadFile(filepath.Join(overrideDir, name))
+		if err == nil {
+			return string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	content, err := defaultPageTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// setPageTemplatesDir switches to the page templates in dir, keeping the embedded defaults if they can't be loaded
+func setPageTemplatesDir(dir string) {
+	if dir == "" {
+		return
+	}
+
+	templates, err := loadPageTemplates(dir)
+	if err != nil {
+		log.Printf("🛈  Couldn't load page templates from '%s', using defaults: %v", dir, err)
+		return
+	}
+	pageTemplates = templates
+}
+
+// prefersJSON reports whether the client asked for JSON rather than HTML
+func prefersJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	jsonIndex := strings.Index(accept, "json")
+	if jsonIndex == -1 {
+		return false
+	}
+
+	htmlIndex := strings.Index(accept, "text/html")
+	return htmlIndex == -1 || jsonIndex < htmlIndex
+}
+
+// renderPage responds with the given page and status code, or with JSON if the client prefers it
+func renderPage(w http.ResponseWriter, r *http.Request, page Page, status int, data PageData) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Vary", "Accept")
+
+	data.Title = pageTitles[page]
+	data.Status = status
+
+	if prefersJSON(r) {
+		response := RedirectErrorResponse{Message: data.Title, Code: page, Slug: data.Slug, StartsAt: data.StartsAt}
+		if data.Error != "" {
+			response.Message = data.Error
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Error encoding response: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pageTemplates[page].ExecuteTemplate(w, "layout", data); err != nil {
+		log.Printf("Error rendering %s page: %v", page, err)
+	}
+}
+
+// renderUnlockPage serves the password form of a protected link
+func renderUnlockPage(w http.ResponseWriter, r *http.Request, slug string, status int, errorMessage string) {
+	renderPage(w, r, PagePasswordRequired, status, PageData{Slug: slug, Error: errorMessage})
+}
+
+// renderComingSoonPage serves the page shown for links that aren't live yet
+func renderComingSoonPage(w http.ResponseWriter, r *http.Request, slug string, startsAt time.Time) {
+	if wait := time.Until(startsAt); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	}
+	renderPage(w, r, PageComingSoon, http.StatusServiceUnavailable, PageData{Slug: slug, StartsAt: &startsAt})
+}
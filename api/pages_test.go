@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"go-link-shortener/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrefersJSON(t *testing.T) {
+	tests := map[string]bool{
+		"":                                  false,
+		"*/*":                               false,
+		"text/html":                         false,
+		"application/json":                  true,
+		"application/problem+json":          true,
+		"application/json, text/html":       true,
+		"text/html, application/json;q=0.9": false,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": false,
+	}
+	for accept, expected := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		r.Header.Set("Accept", accept)
+		if prefersJSON(r) != expected {
+			t.Errorf("prefersJSON(%q) = %t, expected %t", accept, !expected, expected)
+		}
+	}
+}
+
+func TestRenderPage(t *testing.T) {
+	tests := []struct {
+		page   Page
+		status int
+		data   PageData
+		// expectedMessage is the message of the JSON body
+		expectedMessage string
+	}{
+		{PageNotFound, http.StatusNotFound, PageData{Slug: "docs"}, "Link not found"},
+		{PageExpired, http.StatusGone, PageData{Slug: "docs"}, "Link expired"},
+		{PageDisabled, http.StatusGone, PageData{Slug: "docs"}, "Link disabled"},
+		{PagePasswordRequired, http.StatusUnauthorized, PageData{Slug: "docs"}, "Password required"},
+		{PageRateLimited, http.StatusTooManyRequests, PageData{Slug: "docs", Error: "Too many wrong passwords."}, "Too many wrong passwords."},
+		{PageError, http.StatusInternalServerError, PageData{Slug: "docs", Error: "The link couldn't be loaded."}, "The link couldn't be loaded."},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.page), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			r.Header.Set("Accept", "text/html")
+			recorder := httptest.NewRecorder()
+			renderPage(recorder, r, test.page, test.status, test.data)
+
+			if recorder.Code != test.status {
+				t.Errorf("HTML status = %d, expected %d", recorder.Code, test.status)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+				t.Errorf("HTML Content-Type = %q", contentType)
+			}
+			if body := recorder.Body.String(); !strings.Contains(body, "<h1>"+pageTitles[test.page]+"</h1>") {
+				t.Errorf("HTML body doesn't contain the title %q: %s", pageTitles[test.page], body)
+			}
+			if recorder.Header().Get("Cache-Control") != "no-store" || recorder.Header().Get("Vary") != "Accept" {
+				t.Errorf("headers = %v, expected the page not to be cached", recorder.Header())
+			}
+
+			r.Header.Set("Accept", "application/json")
+			recorder = httptest.NewRecorder()
+			renderPage(recorder, r, test.page, test.status, test.data)
+
+			if recorder.Code != test.status {
+				t.Errorf("JSON status = %d, expected %d", recorder.Code, test.status)
+			}
+			var response RedirectErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			expected := RedirectErrorResponse{Message: test.expectedMessage, Code: test.page, Slug: "docs"}
+			if response.Message != expected.Message || response.Code != expected.Code || response.Slug != expected.Slug {
+				t.Errorf("JSON response = %+v, expected %+v", response, expected)
+			}
+		})
+	}
+}
+
+func TestRenderComingSoonPage(t *testing.T) {
+	tests := []struct {
+		name               string
+		startsIn           time.Duration
+		expectedRetryAfter string
+	}{
+		{"later", 90 * time.Second, "90"},
+		{"passed", -time.Minute, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			startsAt := time.Now().Add(test.startsIn)
+			recorder := httptest.NewRecorder()
+			renderComingSoonPage(recorder, httptest.NewRequest(http.MethodGet, "/launch", nil), "launch", startsAt)
+
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, expected 503", recorder.Code)
+			}
+			// the wait is rounded up to whole seconds
+			if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != test.expectedRetryAfter {
+				t.Errorf("Retry-After = %q, expected %q", retryAfter, test.expectedRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRenderUnavailablePage(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Hour)
+	maxVisits := 1
+
+	tests := []struct {
+		name         string
+		link         models.Link
+		expectedCode Page
+	}{
+		{"deactivated", models.Link{IsActive: false}, PageDisabled},
+		{"expired", models.Link{IsActive: false, ExpiresAt: &expiredAt}, PageExpired},
+		{"expired before the worker ran", models.Link{IsActive: true, ExpiresAt: &expiredAt}, PageExpired},
+		{"used up", models.Link{IsActive: false, MaxVisits: &maxVisits, Visits: 1}, PageExpired},
+		{"used up by a concurrent visit", models.Link{IsActive: true, MaxVisits: &maxVisits}, PageExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			r.Header.Set("Accept", "application/json")
+			recorder := httptest.NewRecorder()
+			renderUnavailablePage(recorder, r, test.link, "docs")
+
+			var response RedirectErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			if recorder.Code != http.StatusGone || response.Code != test.expectedCode {
+				t.Errorf("page = %d %q, expected 410 %q", recorder.Code, response.Code, test.expectedCode)
+			}
+		})
+	}
+}
+
+func TestLoadPageTemplatesOverride(t *testing.T) {
+	dir := t.TempDir()
+	content := `{{define "content"}}<p>Nothing at {{.Slug}}, try the <a href="/">home page</a>.</p>{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "not_found.html"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := loadPageTemplates(dir)
+	if err != nil {
+		t.Fatalf("loadPageTemplates error = %v", err)
+	}
+
+	var notFound, expired strings.Builder
+	if err := templates[PageNotFound].ExecuteTemplate(&notFound, "layout", PageData{Slug: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := templates[PageExpired].ExecuteTemplate(&expired, "layout", PageData{Slug: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(notFound.String(), "Nothing at docs") {
+		t.Errorf("not_found page doesn't use the override: %s", notFound.String())
+	}
+	if !strings.Contains(expired.String(), "<html") {
+		t.Errorf("expired page doesn't fall back to the embedded default: %s", expired.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "expired.html"), []byte(`{{define "content"}}{{.Slug`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPageTemplates(dir); err == nil {
+		t.Error("loadPageTemplates accepted a broken override")
+	}
+}
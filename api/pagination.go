@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"go-link-shortener/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// limit: Page size (default 100, max 1000)
+// cursor: The next_cursor of the previous page, empty for the first page
+// sort: The field to sort by, the fields depend on the list
+// order: asc or desc, the default depends on the sort field
+type ListRequest struct {
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	Sort   string `json:"sort,omitempty"`
+	Order  string `json:"order,omitempty"`
+}
+
+// params converts the request to the models' list parameters
+func (l ListRequest) params() models.ListParams {
+	return models.ListParams{Limit: l.Limit, Cursor: l.Cursor, Sort: l.Sort, Order: l.Order}
+}
+
+// listRequestFromQuery reads the paging parameters of a GET list endpoint
+func listRequestFromQuery(query url.Values) (ListRequest, error) {
+	request := ListRequest{
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if request.Limit, err = strconv.Atoi(limit); err != nil {
+			return request, errors.New("limit must be a number")
+		}
+	}
+	return request, nil
+}
+
+// status: Only links that are active, inactive (deactivated) or expired
+// created_from/created_to: Only links created within this time range
+// expires_from/expires_to: Only links expiring within this time range
+// destination_domain: Only links redirecting to this host or one of its subdomains, e.g. "example.com"
+// tag: Only links with this tag
+// folder: Only links in this folder or its subfolders
+type LinkFilterRequest struct {
+	Status            string     `json:"status,omitempty"`
+	CreatedFrom       *time.Time `json:"created_from,omitempty"`
+	CreatedTo         *time.Time `json:"created_to,omitempty"`
+	ExpiresFrom       *time.Time `json:"expires_from,omitempty"`
+	ExpiresTo         *time.Time `json:"expires_to,omitempty"`
+	DestinationDomain string     `json:"destination_domain,omitempty"`
+	Tag               string     `json:"tag,omitempty"`
+	Folder            string     `json:"folder,omitempty"`
+}
+
+var linkStatuses = map[models.LinkStatus]bool{
+	models.LinkStatusActive:   true,
+	models.LinkStatusInactive: true,
+	models.LinkStatusExpired:  true,
+}
+
+// parseTimeQuery reads an optional RFC 3339 timestamp from the query
+func parseTimeQuery(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// parseBoolQuery reads an optional boolean from the query
+func parseBoolQuery(query url.Values, name string) (*bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+	return &b, nil
+}
+
+// linkFilterFromQuery reads the link filters of a GET list endpoint
+func linkFilterFromQuery(query url.Values) (LinkFilterRequest, error) {
+	request := LinkFilterRequest{
+		Status:            query.Get("status"),
+		DestinationDomain: query.Get("destination_domain"),
+		Tag:               query.Get("tag"),
+		Folder:            query.Get("folder"),
+	}
+
+	var err error
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_from", &request.CreatedFrom},
+		{"created_to", &request.CreatedTo},
+		{"expires_from", &request.ExpiresFrom},
+		{"expires_to", &request.ExpiresTo},
+	} {
+		if *param.target, err = parseTimeQuery(query, param.name); err != nil {
+			return request, err
+		}
+	}
+	return request, nil
+}
+
+// filter validates the link filters and converts them to a models.LinkFilter
+func (f LinkFilterRequest) filter() (models.LinkFilter, error) {
+	filter := models.LinkFilter{
+		Status:      models.LinkStatus(f.Status),
+		CreatedFrom: f.CreatedFrom,
+		CreatedTo:   f.CreatedTo,
+		ExpiresFrom: f.ExpiresFrom,
+		ExpiresTo:   f.ExpiresTo,
+	}
+
+	if filter.Status != "" && !linkStatuses[filter.Status] {
+		return filter, errors.New("status must be one of active, inactive or expired")
+	}
+
+	if f.DestinationDomain != "" {
+		host := models.NormalizeHost(f.DestinationDomain)
+		if host == "" || strings.ContainsAny(strings.TrimSpace(f.DestinationDomain), "/?#@ ") {
+			return filter, errors.New("destination_domain must be a host name, e.g. example.com")
+		}
+		filter.DestinationDomain = host
+	}
+
+	if f.Tag != "" {
+		tags, err := normalizeTags([]string{f.Tag})
+		if err != nil {
+			return filter, err
+		}
+		filter.Tag = tags[0]
+	}
+
+	if f.Folder != "" {
+		folder, err := normalizeFolder(f.Folder)
+		if err != nil {
+			return filter, err
+		}
+		if folder != nil {
+			filter.Folder = *folder
+		}
+	}
+
+	return filter, nil
+}
+
+// listErrorStatus returns the status code for an error of a list query: invalid paging parameters are the client's fault
+func listErrorStatus(err error) (int, string) {
+	if errors.Is(err, models.ErrInvalidListParams) {
+		return http.StatusBadRequest, err.Error()
+	}
+	return http.StatusInternalServerError, "Database Error"
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"net/url"
+	"testing"
+)
+
+func TestLinkFilterFromQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected models.LinkFilter
+		valid    bool
+	}{
+		{"empty", "", models.LinkFilter{}, true},
+		{"status", "status=expired", models.LinkFilter{Status: models.LinkStatusExpired}, true},
+		{"unknown status", "status=deleted", models.LinkFilter{}, false},
+		{"destination domain", "destination_domain=Example.COM", models.LinkFilter{DestinationDomain: "example.com"}, true},
+		{"destination url", "destination_domain=https://example.com/docs", models.LinkFilter{}, false},
+		{"tag and folder", "tag=Spring-Campaign&folder=/marketing/", models.LinkFilter{Tag: "spring-campaign", Folder: "marketing"}, true},
+		{"invalid time", "created_from=yesterday", models.LinkFilter{}, false},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		request, err := linkFilterFromQuery(query)
+		var filter models.LinkFilter
+		if err == nil {
+			filter, err = request.filter()
+		}
+		if (err == nil) != test.valid {
+			t.Errorf("%s: error = %v, expected valid = %v", test.name, err, test.valid)
+			continue
+		}
+		if test.valid && filter != test.expected {
+			t.Errorf("%s: filter = %+v, expected %+v", test.name, filter, test.expected)
+		}
+	}
+
+	query, _ := url.ParseQuery("created_from=2024-03-01T00:00:00Z&expires_to=2024-04-01T00:00:00Z")
+	request, err := linkFilterFromQuery(query)
+	if err != nil || request.CreatedFrom == nil || request.ExpiresTo == nil || request.CreatedTo != nil {
+		t.Errorf("time range: request = %+v, error = %v", request, err)
+	}
+}
+
+func TestListRequestFromQuery(t *testing.T) {
+	query, _ := url.ParseQuery("limit=25&cursor=abc&sort=visits&order=asc")
+	request, err := listRequestFromQuery(query)
+	expected := ListRequest{Limit: 25, Cursor: "abc", Sort: "visits", Order: "asc"}
+	if err != nil || request != expected {
+		t.Errorf("listRequestFromQuery = %+v, %v, expected %+v", request, err, expected)
+	}
+
+	query, _ = url.ParseQuery("limit=all")
+	if _, err := listRequestFromQuery(query); err == nil {
+		t.Error("listRequestFromQuery accepted a non-numeric limit")
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"go-link-shortener/models"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var linkPathModes = map[models.LinkPathMode]bool{
+	models.LinkPathExact:       true,
+	models.LinkPathTemplate:    true,
+	models.LinkPathPassthrough: true,
+}
+
+// placeholderPattern matches {1} or {name} placeholders, also in their percent-encoded form
+// since validateAndNormalizeURL escapes braces in paths
+var placeholderPattern = regexp.MustCompile(`(?:\{|%7[Bb])([A-Za-z0-9_]+)(?:\}|%7[Dd])`)
+
+var errLinkPathMismatch = errors.New("path doesn't fit the link")
+
+// acceptsPath reports whether a link can be visited with path segments after its slug
+func acceptsPath(link models.Link, rest string) bool {
+	return rest == "" || (link.PathMode != "" && link.PathMode != models.LinkPathExact)
+}
+
+// applyLinkPath builds the destination for a visit with path segments after the slug, rest is the escaped
+// remainder of the path. errLinkPathMismatch is returned if the segments don't fit the destination's placeholders.
+func applyLinkPath(destination string, mode models.LinkPathMode, rest string) (string, error) {
+	switch mode {
+	case models.LinkPathTemplate:
+		return fillPathTemplate(destination, rest)
+	case models.LinkPathPassthrough:
+		return appendLinkPath(destination, rest)
+	default:
+		if rest != "" {
+			return "", errLinkPathMismatch
+		}
+		return destination, nil
+	}
+}
+
+// fillPathTemplate substitutes the path segments into the destination's placeholders.
+// {1}, {2}, ... are the segments by position, named placeholders are numbered in the order they first appear,
+// so "https://github.com/{org}/{repo}" takes the first segment as org and the second as repo.
+// Every placeholder must be filled and every segment must be used.
+func fillPathTemplate(destination string, rest string) (string, error) {
+	var segments []string
+	if rest != "" {
+		for _, rawSegment := range strings.Split(rest, "/") {
+			segment, err := url.PathUnescape(rawSegment)
+			if err != nil || segment == "" {
+				return "", errLinkPathMismatch
+			}
+			segments = append(segments, segment)
+		}
+	}
+
+	queryStart := strings.Index(destination, "?")
+	fragmentStart := strings.Index(destination, "#")
+	namedPositions := make(map[string]int)
+	used := 0
+
+	var result strings.Builder
+	last := 0
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(destination, -1) {
+		name := destination[match[2]:match[3]]
+
+		position, err := strconv.Atoi(name)
+		if err != nil {
+			if _, ok := namedPositions[name]; !ok {
+				namedPositions[name] = len(namedPositions) + 1
+			}
+			position = namedPositions[name]
+		}
+		if position < 1 || position > len(segments) {
+			return "", errLinkPathMismatch
+		}
+		used = max(used, position)
+
+		value := segments[position-1]
+		inQuery := queryStart >= 0 && match[0] > queryStart && (fragmentStart < 0 || match[0] < fragmentStart)
+		if inQuery {
+			value = url.QueryEscape(value)
+		} else {
+			value = url.PathEscape(value)
+		}
+
+		result.WriteString(destination[last:match[0]])
+		result.WriteString(value)
+		last = match[1]
+	}
+	result.WriteString(destination[last:])
+
+	if used != len(segments) {
+		return "", errLinkPathMismatch
+	}
+	return result.String(), nil
+}
+
+// appendLinkPath appends the escaped rest of the path to the destination's path, keeping its query and fragment
+func appendLinkPath(destination string, rest string) (string, error) {
+	if rest == "" {
+		return destination, nil
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil || u.Opaque != "" {
+		return "", errLinkPathMismatch
+	}
+
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + rest
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return "", errLinkPathMismatch
+	}
+	u.Path = path
+	u.RawPath = rawPath
+	return u.String(), nil
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"testing"
+)
+
+func TestApplyLinkPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		mode        models.LinkPathMode
+		rest        string
+		expected    string
+		mismatch    bool
+	}{
+		{"exact without path", "https://example.com/", models.LinkPathExact, "", "https://example.com/", false},
+		{"exact with path", "https://example.com/", models.LinkPathExact, "extra", "", true},
+		{"positional", "https://jira.example.com/browse/{1}", models.LinkPathTemplate, "ABC-123", "https://jira.example.com/browse/ABC-123", false},
+		{"normalized braces", "https://jira.example.com/browse/%7B1%7D", models.LinkPathTemplate, "ABC-123", "https://jira.example.com/browse/ABC-123", false},
+		{"named", "https://github.com/{org}/{repo}/issues", models.LinkPathTemplate, "golang/go", "https://github.com/golang/go/issues", false},
+		{"reused placeholder", "https://example.com/{1}?q={1}", models.LinkPathTemplate, "a b", "https://example.com/a%20b?q=a+b", false},
+		{"query escaping", "https://example.com/search?q={1}#top", models.LinkPathTemplate, "x%26y", "https://example.com/search?q=x%26y#top", false},
+		{"missing segment", "https://github.com/{org}/{repo}", models.LinkPathTemplate, "golang", "", true},
+		{"unused segment", "https://jira.example.com/browse/{1}", models.LinkPathTemplate, "ABC-123/extra", "", true},
+		{"passthrough", "https://docs.example.com/api/?v=2#intro", models.LinkPathPassthrough, "users/list", "https://docs.example.com/api/users/list?v=2#intro", false},
+		{"passthrough keeps encoding", "https://example.com", models.LinkPathPassthrough, "a%2Fb", "https://example.com/a%2Fb", false},
+		{"passthrough without path", "https://example.com/docs", models.LinkPathPassthrough, "", "https://example.com/docs", false},
+	}
+
+	for _, test := range tests {
+		result, err := applyLinkPath(test.destination, test.mode, test.rest)
+		if test.mismatch {
+			if err == nil {
+				t.Errorf("%s: expected a mismatch, got %q", test.name, result)
+			}
+			continue
+		}
+		if err != nil || result != test.expected {
+			t.Errorf("%s: applyLinkPath = %q, %v, expected %q", test.name, result, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"gorm.io/gorm"
+)
+
+// redirect_to: Left empty for password-protected links
+// owner: The display name of the link's key, only set if the key has one
+type LinkPreviewResponse struct {
+	Slug        string    `json:"slug"`
+	RedirectTo  string    `json:"redirect_to,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Owner       string    `json:"owner,omitempty"`
+	HasPassword bool      `json:"has_password"`
+}
+
+// previewSlug returns the slug of a "/{slug}+" or "/{namespace}/{slug}+" preview path, and whether the path is one.
+// Only the slug can carry the "+", at the end of the rest of a template or passthrough path it's part of the rest.
+func previewSlug(path linkPath) (linkPath, bool) {
+	if path.Rest != "" {
+		return path, false
+	}
+	slug, found := strings.CutSuffix(path.Slug, "+")
+	path.Slug = slug
+	return path, found && slug != ""
+}
+
+// previewHandler serves the preview of the link at /preview/{slug} or /preview/{namespace}/{slug}
+func previewHandler(w http.ResponseWriter, r *http.Request) {
+	db := database.GetDB()
+
+	slug := strings.TrimSuffix(chi.URLParam(r, "*"), "/")
+	path, err := parseRequestPath(db, r, slug)
+	if err != nil {
+		writeRedirectLookupError(w, r, path.Domain, slug, err)
+		return
+	}
+	servePreview(w, r, db, path)
+}
+
+// servePreview shows where a link redirects to without following it. Previews aren't counted as visits.
+func servePreview(w http.ResponseWriter, r *http.Request, db *gorm.DB, path linkPath) {
+	slug := path.Display()
+
+	// previews are of the link itself, so there's no rest of the path to fill in
+	if path.Rest != "" {
+		writeRedirectLookupError(w, r, path.Domain, slug+"/"+path.Rest, gorm.ErrRecordNotFound)
+		return
+	}
+
+	linkObj, _, err := resolveRedirectLink(db, path.Scope(), path.Slug)
+	if err != nil {
+		writeRedirectLookupError(w, r, path.Domain, slug, err)
+		return
+	}
+
+	if !isLinkAvailable(*linkObj) {
+		renderUnavailablePage(w, r, *linkObj, slug)
+		return
+	}
+
+	if !hasStarted(*linkObj) {
+		renderComingSoonPage(w, r, slug, *linkObj.StartsAt)
+		return
+	}
+
+	preview := LinkPreviewResponse{
+		Slug:        slug,
+		CreatedAt:   linkObj.CreatedAt,
+		HasPassword: linkObj.PasswordHash != nil,
+	}
+	// the destination of a protected link is part of what the password protects
+	if !preview.HasPassword {
+		preview.RedirectTo = linkObj.RedirectTo
+	}
+	if key := models.SearchKeyByID(db, linkObj.CreatedBy); key != nil && key.DisplayName != nil {
+		preview.Owner = *key.DisplayName
+	}
+
+	if prefersJSON(r) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Vary", "Accept")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			log.Printf("Error encoding response: %v", err)
+		}
+		return
+	}
+
+	renderPage(w, r, PagePreview, http.StatusOK, PageData{Slug: slug, Preview: &preview})
+}
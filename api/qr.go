@@ -0,0 +1,329 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	qrDefaultSize   = 256
+	qrMinSize       = 64
+	qrMaxSize       = 2048
+	qrDefaultMargin = 4
+	qrMaxMargin     = 16
+)
+
+var qrRecoveryLevels = map[string]qrcode.RecoveryLevel{
+	"low":     qrcode.Low,
+	"medium":  qrcode.Medium,
+	"high":    qrcode.High,
+	"highest": qrcode.Highest,
+}
+
+// QROptions describes how a QR code is rendered
+type QROptions struct {
+	Format     string
+	Size       int
+	Level      qrcode.RecoveryLevel
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// LinkQRCodeHandler renders a QR code for the public short URL of a link.
+// @Summary Generate a QR code for a link
+// @Description Renders a QR code that encodes the link's short URL on its domain (PUBLIC_SITE_URL for the default domain), as PNG or SVG. With attribution=true, the encoded URL carries src=qr, which is recorded on the visits it brings in.
+// @Tags links
+// @Produce png
+// @Produce image/svg+xml
+// @Security ApiKeyAuth
+// @Param shortened query string true "Shortened URL of the link, as domain/shortened for links on other domains"
+// @Param format query string false "png (default) or svg"
+// @Param size query int false "Width and height in pixels, 64 to 2048 (default 256)"
+// @Param level query string false "Error correction level: low, medium (default), high or highest"
+// @Param margin query int false "Quiet zone around the code in modules, 0 to 16 (default 4)"
+// @Param fg query string false "Foreground color as a hex code (default 000000)"
+// @Param bg query string false "Background color as a hex code (default ffffff)"
+// @Param attribution query bool false "Add src=qr to the encoded URL"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/qr [get]
+func LinkQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+	query := r.URL.Query()
+
+	options, err := parseQROptions(query)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	shortened := query.Get("shortened")
+	link, err := RetrieveLink(database.GetDB(), shortened)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Link not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Shortened: %s", shortened),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	publicURL, err := publicShortURL(utils.LoadEnv(), *link)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if query.Get("attribution") == "true" {
+		publicURL += "?" + url.Values{lib.QR_SOURCE_PARAM: {lib.QR_SOURCE_VALUE}}.Encode()
+	}
+
+	code, err := qrcode.New(publicURL, options.Level)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to generate QR code",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	code.DisableBorder = true
+
+	// long URLs and high recovery levels need more modules than a small PNG has pixels
+	if minSize := qrMinimumSize(code.Bitmap(), options); options.Format != "svg" && options.Size < minSize {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   fmt.Sprintf("size must be at least %d for this link with the requested level and margin", minSize),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	var body []byte
+	var contentType string
+	if options.Format == "svg" {
+		body = renderQRSVG(code.Bitmap(), options)
+		contentType = "image/svg+xml"
+	} else {
+		body, err = renderQRPNG(code.Bitmap(), options)
+		contentType = "image/png"
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to render QR code",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", link.Shortened+"."+options.Format))
+	w.Write(body)
+}
+
+// publicShortURL returns the public URL a link is reachable at, on its domain if it has one
+func publicShortURL(env *utils.Env, link models.Link) (string, error) {
+	base, err := shortURLBase(env, link.Domain)
+	if err != nil {
+		return "", err
+	}
+	return base + "/" + namespacedSlug(link.Namespace, link.Shortened), nil
+}
+
+// parseQROptions reads the QR code options from the query, applying defaults for missing ones
+func parseQROptions(query url.Values) (QROptions, error) {
+	options := QROptions{
+		Format:     "png",
+		Size:       qrDefaultSize,
+		Level:      qrcode.Medium,
+		Margin:     qrDefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+
+	if format := query.Get("format"); format != "" {
+		if format != "png" && format != "svg" {
+			return options, errors.New("format must be png or svg")
+		}
+		options.Format = format
+	}
+
+	if rawSize := query.Get("size"); rawSize != "" {
+		size, err := strconv.Atoi(rawSize)
+		if err != nil || size < qrMinSize || size > qrMaxSize {
+			return options, fmt.Errorf("size must be between %d and %d", qrMinSize, qrMaxSize)
+		}
+		options.Size = size
+	}
+
+	if rawLevel := query.Get("level"); rawLevel != "" {
+		level, ok := qrRecoveryLevels[rawLevel]
+		if !ok {
+			return options, errors.New("level must be low, medium, high or highest")
+		}
+		options.Level = level
+	}
+
+	if rawMargin := query.Get("margin"); rawMargin != "" {
+		margin, err := strconv.Atoi(rawMargin)
+		if err != nil || margin < 0 || margin > qrMaxMargin {
+			return options, fmt.Errorf("margin must be between 0 and %d", qrMaxMargin)
+		}
+		options.Margin = margin
+	}
+
+	var err error
+	if rawColor := query.Get("fg"); rawColor != "" {
+		if options.Foreground, err = parseHexColor(rawColor); err != nil {
+			return options, fmt.Errorf("invalid fg: %v", err)
+		}
+	}
+	if rawColor := query.Get("bg"); rawColor != "" {
+		if options.Background, err = parseHexColor(rawColor); err != nil {
+			return options, fmt.Errorf("invalid bg: %v", err)
+		}
+	}
+
+	return options, nil
+}
+
+// parseHexColor parses an RGB color in "rrggbb" or "rgb" form, with or without a leading "#"
+func parseHexColor(raw string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(raw, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, errors.New("color must be a 3 or 6 digit hex code")
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, errors.New("color must be a 3 or 6 digit hex code")
+	}
+
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, nil
+}
+
+// qrMinimumSize returns the smallest PNG size that fits every module of a code and its margin, one pixel per module
+func qrMinimumSize(bitmap [][]bool, options QROptions) int {
+	return len(bitmap) + 2*options.Margin
+}
+
+// renderQRPNG draws the QR code modules into a size x size PNG.
+// Modules are scaled by a whole number of pixels to keep their edges sharp, and the code is centered.
+func renderQRPNG(bitmap [][]bool, options QROptions) ([]byte, error) {
+	modules := qrMinimumSize(bitmap, options)
+	scale := options.Size / modules
+	if scale < 1 {
+		return nil, fmt.Errorf("size %d is too small for a code with %d modules", options.Size, modules)
+	}
+	offset := (options.Size-scale*modules)/2 + scale*options.Margin
+
+	palette := color.Palette{options.Background, options.Foreground}
+	img := image.NewPaletted(image.Rect(0, 0, options.Size, options.Size), palette)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.SetColorIndex(offset+x*scale+px, offset+y*scale+py, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderQRSVG draws the QR code modules as a single SVG path, one unit per module
+func renderQRSVG(bitmap [][]bool, options QROptions) []byte {
+	modules := len(bitmap) + 2*options.Margin
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+options.Margin, y+options.Margin)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		options.Size, options.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, modules, modules, hexColor(options.Background))
+	fmt.Fprintf(&buf, `<path d="%s" fill="%s"/>`, path.String(), hexColor(options.Foreground))
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// hexColor formats a color as "#rrggbb"
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
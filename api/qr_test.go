@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected color.RGBA
+		valid    bool
+	}{
+		{"#ff8000", color.RGBA{R: 0xff, G: 0x80, A: 0xff}, true},
+		{"FF8000", color.RGBA{R: 0xff, G: 0x80, A: 0xff}, true},
+		{"#f80", color.RGBA{R: 0xff, G: 0x88, A: 0xff}, true},
+		{"#ff80", color.RGBA{}, false},
+		{"gggggg", color.RGBA{}, false},
+		{"", color.RGBA{}, false},
+	}
+
+	for _, test := range tests {
+		c, err := parseHexColor(test.raw)
+		if (err == nil) != test.valid || (test.valid && c != test.expected) {
+			t.Errorf("parseHexColor(%q) = %v, %v", test.raw, c, err)
+		}
+	}
+}
+
+func TestParseQROptionsSize(t *testing.T) {
+	tests := map[string]bool{
+		"":     true,
+		"63":   false,
+		"64":   true,
+		"2048": true,
+		"2049": false,
+		"big":  false,
+	}
+
+	for size, valid := range tests {
+		query := url.Values{}
+		if size != "" {
+			query.Set("size", size)
+		}
+		if _, err := parseQROptions(query); (err == nil) != valid {
+			t.Errorf("parseQROptions(size=%q) error = %v, expected valid = %t", size, err, valid)
+		}
+	}
+}
+
+func TestRenderQRPNGSize(t *testing.T) {
+	code, err := qrcode.New("https://example.com/"+strings.Repeat("a", 500), qrcode.Highest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code.DisableBorder = true
+	options := QROptions{Size: qrMinSize, Margin: qrMaxMargin, Foreground: color.RGBA{A: 0xff}, Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+
+	minSize := qrMinimumSize(code.Bitmap(), options)
+	if minSize <= qrMinSize {
+		t.Fatalf("qrMinimumSize = %d, expected more than %d for a long URL", minSize, qrMinSize)
+	}
+	if _, err := renderQRPNG(code.Bitmap(), options); err == nil {
+		t.Error("renderQRPNG rendered a code with more modules than pixels")
+	}
+
+	options.Size = minSize
+	body, err := renderQRPNG(code.Bitmap(), options)
+	if err != nil {
+		t.Fatalf("renderQRPNG at the minimum size error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil || img.Bounds().Dx() != minSize || img.Bounds().Dy() != minSize {
+		t.Errorf("renderQRPNG = %v, %v, expected a %dx%d PNG", img.Bounds(), err, minSize, minSize)
+	}
+}
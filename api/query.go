@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"go-link-shortener/models"
+	"net/url"
+	"strings"
+)
+
+var queryForwardPolicies = map[models.QueryForwardPolicy]bool{
+	models.QueryForwardNone:        true,
+	models.QueryForwardIncoming:    true,
+	models.QueryForwardDestination: true,
+	models.QueryForwardAllowlist:   true,
+}
+
+// queryParam is a decoded query parameter, kept in a slice so that parameter order is preserved
+type queryParam struct {
+	key   string
+	value string
+	raw   string
+}
+
+// parseQueryParams splits a raw query string into its parameters, skipping ones that aren't validly encoded
+func parseQueryParams(rawQuery string) []queryParam {
+	var params []queryParam
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(part, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			continue
+		}
+		params = append(params, queryParam{key: key, value: value, raw: part})
+	}
+	return params
+}
+
+// forwardQuery merges the query string of a visit into a destination URL according to the link's policy.
+// Destination parameters keep their original encoding and order, forwarded parameters are re-encoded and appended.
+// The destination's fragment is preserved.
+func forwardQuery(destination string, rawIncoming string, policy models.QueryForwardPolicy, allowlist []string) string {
+	if policy == "" || policy == models.QueryForwardNone || rawIncoming == "" {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	incoming := parseQueryParams(rawIncoming)
+	if policy == models.QueryForwardAllowlist {
+		allowed := make(map[string]bool, len(allowlist))
+		for _, key := range allowlist {
+			allowed[key] = true
+		}
+		forwarded := incoming[:0]
+		for _, param := range incoming {
+			if allowed[param.key] {
+				forwarded = append(forwarded, param)
+			}
+		}
+		incoming = forwarded
+	}
+	if len(incoming) == 0 {
+		return destination
+	}
+
+	existing := parseQueryParams(u.RawQuery)
+	destinationKeys := make(map[string]bool, len(existing))
+	for _, param := range existing {
+		destinationKeys[param.key] = true
+	}
+	incomingKeys := make(map[string]bool, len(incoming))
+	for _, param := range incoming {
+		incomingKeys[param.key] = true
+	}
+
+	var parts []string
+	for _, param := range existing {
+		if policy != models.QueryForwardDestination && incomingKeys[param.key] {
+			continue
+		}
+		parts = append(parts, param.raw)
+	}
+	for _, param := range incoming {
+		if policy == models.QueryForwardDestination && destinationKeys[param.key] {
+			continue
+		}
+		parts = append(parts, url.QueryEscape(param.key)+"="+url.QueryEscape(param.value))
+	}
+
+	u.RawQuery = strings.Join(parts, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// forwardQueryKeys returns the allowlisted keys of a link
+func forwardQueryKeys(link models.Link) []string {
+	if link.ForwardQueryKeys == nil || *link.ForwardQueryKeys == "" {
+		return nil
+	}
+	return strings.Split(*link.ForwardQueryKeys, ",")
+}
+
+// validateQueryForwarding checks a forwarding policy and its allowlist, returning the keys as stored on the link
+func validateQueryForwarding(policy models.QueryForwardPolicy, keys []string) (*string, error) {
+	if !queryForwardPolicies[policy] {
+		return nil, errors.New("forward_query must be one of none, incoming, destination or allowlist")
+	}
+
+	cleaned := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || strings.Contains(key, ",") {
+			return nil, errors.New("forward_query_keys must be non-empty keys without commas")
+		}
+		cleaned = append(cleaned, key)
+	}
+
+	if policy == models.QueryForwardAllowlist && len(cleaned) == 0 {
+		return nil, errors.New("forward_query_keys is required for the allowlist policy")
+	}
+	if len(cleaned) == 0 {
+		return nil, nil
+	}
+
+	joined := strings.Join(cleaned, ",")
+	if len(joined) > 1024 {
+		return nil, errors.New("forward_query_keys is too long")
+	}
+	return &joined, nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"testing"
+)
+
+func TestForwardQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		incoming    string
+		policy      models.QueryForwardPolicy
+		allowlist   []string
+		expected    string
+	}{
+		{"disabled", "https://example.com/docs", "page=2", models.QueryForwardNone, nil, "https://example.com/docs"},
+		{"no incoming query", "https://example.com/docs?a=1", "", models.QueryForwardIncoming, nil, "https://example.com/docs?a=1"},
+		{"appended", "https://example.com/docs", "page=2", models.QueryForwardIncoming, nil, "https://example.com/docs?page=2"},
+		{"incoming wins", "https://example.com/?a=1&b=2", "b=3&c=4", models.QueryForwardIncoming, nil, "https://example.com/?a=1&b=3&c=4"},
+		{"destination wins", "https://example.com/?a=1&b=2", "b=3&c=4", models.QueryForwardDestination, nil, "https://example.com/?a=1&b=2&c=4"},
+		{"allowlist", "https://example.com/?a=1", "utm_source=x&secret=y&a=2", models.QueryForwardAllowlist, []string{"utm_source", "a"}, "https://example.com/?utm_source=x&a=2"},
+		{"fragment preserved", "https://example.com/page#section", "page=2", models.QueryForwardIncoming, nil, "https://example.com/page?page=2#section"},
+		{"encoding", "https://example.com/?q=a%20b", "name=J%C3%BCrgen&x=a%26b+c", models.QueryForwardIncoming, nil, "https://example.com/?q=a%20b&name=J%C3%BCrgen&x=a%26b+c"},
+		{"repeated keys", "https://example.com/?tag=a", "tag=b&tag=c", models.QueryForwardIncoming, nil, "https://example.com/?tag=b&tag=c"},
+		{"invalid encoding skipped", "https://example.com/", "bad=%zz&ok=1", models.QueryForwardIncoming, nil, "https://example.com/?ok=1"},
+	}
+
+	for _, test := range tests {
+		if result := forwardQuery(test.destination, test.incoming, test.policy, test.allowlist); result != test.expected {
+			t.Errorf("%s: forwardQuery = %q, expected %q", test.name, result, test.expected)
+		}
+	}
+}
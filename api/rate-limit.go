@@ -0,0 +1,90 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// failedAttemptLimiter blocks a client after too many failed attempts within a time window.
+// State is kept in memory, so limits are per instance and reset on restart.
+type failedAttemptLimiter struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	failures    map[string][]time.Time
+	// lastSweep is when the failures of every client were last pruned, see sweep
+	lastSweep time.Time
+}
+
+func newFailedAttemptLimiter(maxFailures int, window time.Duration) *failedAttemptLimiter {
+	return &failedAttemptLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		failures:    make(map[string][]time.Time),
+		lastSweep:   time.Now(),
+	}
+}
+
+// RetryAfter returns how long the client has to wait before trying again, 0 if it isn't blocked
+func (l *failedAttemptLimiter) RetryAfter(client string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := l.prune(client, now)
+	if len(recent) < l.maxFailures {
+		return 0
+	}
+
+	// blocked until the oldest failure that still counts leaves the window
+	return recent[len(recent)-l.maxFailures].Add(l.window).Sub(now)
+}
+
+// RecordFailure counts a failed attempt for the client
+func (l *failedAttemptLimiter) RecordFailure(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.failures[client] = append(l.prune(client, now), now)
+	l.sweep(now)
+}
+
+// Reset forgets the failed attempts of the client
+func (l *failedAttemptLimiter) Reset(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, client)
+}
+
+// prune drops the client's failures that are outside of the window. Callers must hold the lock.
+func (l *failedAttemptLimiter) prune(client string, now time.Time) []time.Time {
+	failures := l.failures[client]
+
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(failures) && !failures[i].After(cutoff) {
+		i++
+	}
+	failures = failures[i:]
+
+	if len(failures) == 0 {
+		delete(l.failures, client)
+		return nil
+	}
+	l.failures[client] = failures
+	return failures
+}
+
+// sweep prunes the failures of every client once per window, so that clients that never come back are forgotten.
+// Callers must hold the lock.
+func (l *failedAttemptLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for client := range l.failures {
+		l.prune(client, now)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailedAttemptLimiter(t *testing.T) {
+	limiter := newFailedAttemptLimiter(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		limiter.RecordFailure("1.2.3.4")
+	}
+	if retryAfter := limiter.RetryAfter("1.2.3.4"); retryAfter != 0 {
+		t.Errorf("RetryAfter below the limit = %v, expected 0", retryAfter)
+	}
+
+	limiter.RecordFailure("1.2.3.4")
+	if retryAfter := limiter.RetryAfter("1.2.3.4"); retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("RetryAfter at the limit = %v, expected up to a minute", retryAfter)
+	}
+	if retryAfter := limiter.RetryAfter("5.6.7.8"); retryAfter != 0 {
+		t.Errorf("RetryAfter of another client = %v, expected 0", retryAfter)
+	}
+
+	limiter.Reset("1.2.3.4")
+	if retryAfter := limiter.RetryAfter("1.2.3.4"); retryAfter != 0 {
+		t.Errorf("RetryAfter after Reset = %v, expected 0", retryAfter)
+	}
+}
+
+func TestFailedAttemptLimiterExpiry(t *testing.T) {
+	limiter := newFailedAttemptLimiter(2, time.Minute)
+	now := time.Now()
+
+	// failures outside of the window no longer count
+	limiter.failures["1.2.3.4"] = []time.Time{now.Add(-2 * time.Minute), now.Add(-90 * time.Second)}
+	if retryAfter := limiter.RetryAfter("1.2.3.4"); retryAfter != 0 {
+		t.Errorf("RetryAfter with expired failures = %v, expected 0", retryAfter)
+	}
+
+	// the block ends when the oldest failure that counts leaves the window
+	limiter.failures["1.2.3.4"] = []time.Time{now.Add(-50 * time.Second), now.Add(-10 * time.Second)}
+	if retryAfter := limiter.RetryAfter("1.2.3.4"); retryAfter <= 0 || retryAfter > 10*time.Second {
+		t.Errorf("RetryAfter = %v, expected at most 10s", retryAfter)
+	}
+
+	// clients that never come back are swept once per window
+	limiter.failures["5.6.7.8"] = []time.Time{now.Add(-2 * time.Minute)}
+	limiter.lastSweep = now.Add(-2 * time.Minute)
+	limiter.RecordFailure("9.9.9.9")
+	if _, ok := limiter.failures["5.6.7.8"]; ok {
+		t.Error("RecordFailure didn't sweep a client whose failures expired")
+	}
+	if len(limiter.failures["1.2.3.4"]) != 2 {
+		t.Errorf("the sweep dropped failures within the window: %v", limiter.failures["1.2.3.4"])
+	}
+}
+
+func TestGetClientKey(t *testing.T) {
+	tests := map[string]string{
+		"1.2.3.4:5678":      "1.2.3.4",
+		"[2001:db8::1]:443": "2001:db8::1",
+		"1.2.3.4":           "1.2.3.4",
+		"@/run/app.sock":    "@/run/app.sock",
+		"proxy-a":           "proxy-a",
+	}
+	for remoteAddr, expected := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/docs", nil)
+		r.RemoteAddr = remoteAddr
+		if key := GetClientKey(r); key != expected {
+			t.Errorf("GetClientKey(%q) = %q, expected %q", remoteAddr, key, expected)
+		}
+	}
+
+	// clients whose address can't be parsed don't share their failures
+	limiter := newFailedAttemptLimiter(1, time.Minute)
+	a := httptest.NewRequest(http.MethodPost, "/docs", nil)
+	a.RemoteAddr = "proxy-a"
+	b := httptest.NewRequest(http.MethodPost, "/docs", nil)
+	b.RemoteAddr = "proxy-b"
+	limiter.RecordFailure(GetClientKey(a))
+	if limiter.RetryAfter(GetClientKey(a)) <= 0 || limiter.RetryAfter(GetClientKey(b)) != 0 {
+		t.Error("clients with unparseable addresses share a failure count")
+	}
+}
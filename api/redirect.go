@@ -0,0 +1,353 @@
+package api
+
+import (
+	"errors"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi"
+	"gorm.io/gorm"
+)
+
+func RedirectRouter() chi.Router {
+	r := chi.NewRouter()
+
+	env := utils.LoadEnv()
+	setPageTemplatesDir(env.PAGE_TEMPLATES_DIR)
+	openGeoIPDatabase(env.GEOIP_DB_PATH)
+
+	// a wildcard, so that links in namespaces such as /preview/eng/docs can be previewed as well
+	r.Get(lib.ROUTES.Preview+"/*", previewHandler)
+	r.Get("/*", redirectHandler(env))
+	// password-protected links post their unlock form back to their own slug
+	r.Post("/*", unlockHandler(env))
+
+	return r
+}
+
+// redirectHandler resolves the requested slug and redirects the visitor to its destination
+func redirectHandler(env *utils.Env) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// blank path, redirect to docs
+		if r.URL.Path == "/" {
+			if env.ENABLE_DOCS == "false" {
+				http.Redirect(w, r, lib.ROUTES.NotFound, http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, lib.ROUTES.Docs+"/", http.StatusFound)
+			return
+		}
+
+		db := database.GetDB()
+
+		// remove leading slash
+		fixedPath := r.URL.Path[1:]
+
+		// slugs are looked up on the domain the request was sent to, inside the namespace the path starts with if any.
+		// Template and passthrough links use the rest of the path.
+		path, err := parseRequestPath(db, r, r.URL.EscapedPath()[1:])
+		if err != nil {
+			writeRedirectLookupError(w, r, path.Domain, fixedPath, err)
+			return
+		}
+
+		// appending "+" to a slug previews the link instead of following it
+		if previewPath, ok := previewSlug(path); ok {
+			servePreview(w, r, db, previewPath)
+			return
+		}
+		slug, rest := path.Display(), path.Rest
+		linkObj, historicalSlug, err := resolveRedirectLink(db, path.Scope(), path.Slug)
+
+		if err == nil && !acceptsPath(*linkObj, rest) {
+			err = gorm.ErrRecordNotFound
+		}
+		if err != nil {
+			writeRedirectLookupError(w, r, path.Domain, fixedPath, err)
+			return
+		}
+
+		// expired, deactivated or used up links send visitors to their fallback, if there is one
+		if !isLinkAvailable(*linkObj) {
+			serveFallback(w, r, db, env, *linkObj, slug)
+			return
+		}
+
+		// old slugs of renamed links can point visitors at the current slug instead of the destination
+		if historicalSlug != nil && historicalSlug.RedirectToCurrent {
+			currentURL := "/" + namespacedSlug(path.Namespace, linkObj.Shortened)
+			if rest != "" {
+				currentURL += "/" + rest
+			}
+			// keep the query string, the current slug may forward it to the destination
+			if r.URL.RawQuery != "" {
+				currentURL += "?" + r.URL.RawQuery
+			}
+			// the old slug can be released or reclaimed later, so it's only cached like the link's own redirects
+			statusCode := redirectStatusCode(*linkObj, path.Domain, env)
+			w.Header().Set("Cache-Control", redirectCacheControl(statusCode))
+			http.Redirect(w, r, currentURL, statusCode)
+			return
+		}
+
+		// links scheduled to start later aren't live yet, visits before the start aren't counted
+		if !hasStarted(*linkObj) {
+			serveNotStarted(w, r, *linkObj, slug)
+			return
+		}
+
+		// password-protected links show the unlock form until the browser holds a valid unlock cookie
+		if linkObj.PasswordHash != nil && !hasValidUnlockCookie(r, *linkObj, env) {
+			renderUnlockPage(w, r, slug, http.StatusUnauthorized, "")
+			return
+		}
+
+		redirectToLink(w, r, db, env, *linkObj, slug, rest, redirectStatusCode(*linkObj, path.Domain, env))
+	}
+}
+
+// writeRedirectLookupError responds to a slug that couldn't be resolved.
+// Unknown slugs on a domain with a not found URL are redirected there instead of showing the 404 page.
+func writeRedirectLookupError(w http.ResponseWriter, r *http.Request, domain *models.Domain, slug string, err error) {
+	// check if err is "record not found"
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if domain != nil && domain.NotFoundURL != nil {
+			w.Header().Set("Cache-Control", redirectCacheControl(http.StatusFound))
+			http.Redirect(w, r, *domain.NotFoundURL, http.StatusFound)
+			return
+		}
+		renderPage(w, r, PageNotFound, http.StatusNotFound, PageData{Slug: slug})
+		return
+	}
+	// if not, return internal server error
+	log.Printf("Error resolving '%s': %v", slug, err)
+	renderPage(w, r, PageError, http.StatusInternalServerError, PageData{
+		Slug:  slug,
+		Error: "The link couldn't be loaded. Please try again later.",
+	})
+}
+
+// redirectToLink records a visit to the link and redirects to its destination with the given status code.
+// The destination is picked by the first of the link's rules that matches the visitor, falling back to RedirectTo.
+// rest is the escaped path after the slug, which template and passthrough links build the destination from.
+func redirectToLink(w http.ResponseWriter, r *http.Request, db *gorm.DB, env *utils.Env, link models.Link, slug string, rest string, statusCode int) {
+	destination := link.RedirectTo
+	cacheControl := redirectCacheControl(statusCode)
+	details := visitDetails(r, slug)
+
+	rules, err := models.RetrieveLinkRules(db, link.ID)
+	if err != nil {
+		log.Printf("Error retrieving rules for '%s': %v", slug, err)
+	}
+	if len(rules) > 0 {
+		if rule := matchLinkRule(rules, newRuleVisitor(r, rules)); rule != nil {
+			destination = rule.RedirectTo
+			details.RuleID = &rule.ID
+		}
+		// the destination depends on the visitor, so it must not be cached
+		cacheControl = redirectCacheControl(http.StatusFound)
+	}
+
+	// a matching rule takes precedence over the A/B split
+	if details.RuleID == nil {
+		variants, err := models.RetrieveLinkVariants(db, link.ID)
+		if err != nil {
+			log.Printf("Error retrieving variants for '%s': %v", slug, err)
+		}
+		if len(variants) > 0 {
+			if variant := pickVariant(variants, variantRoll(w, r, link)); variant != nil {
+				destination = variant.RedirectTo
+				details.VariantID = &variant.ID
+				// every visit has to reach the server to be split
+				cacheControl = redirectCacheControl(http.StatusFound)
+			}
+		}
+	}
+
+	// template and passthrough links build the destination from the rest of the path
+	destination, err = applyLinkPath(destination, link.PathMode, rest)
+	if err != nil {
+		renderPage(w, r, PageNotFound, http.StatusNotFound, PageData{Slug: slug + "/" + rest})
+		return
+	}
+
+	destination = forwardQuery(destination, r.URL.RawQuery, link.ForwardQuery, forwardQueryKeys(link))
+
+	// increment visits, update the last visited time, and add a new record to the link_visits table
+	err = models.RecordVisit(db, link.ID, details)
+	if errors.Is(err, models.ErrVisitLimitReached) {
+		// the link was used up, possibly by a concurrent visit, and will be deactivated by the expiration worker
+		serveFallback(w, r, db, env, link, slug)
+		return
+	}
+	if err != nil {
+		log.Printf("Error recording visit for '%s': %v", slug, err)
+		// a visit that wasn't counted can't be allowed on a visit-limited link, it could be redeemed without limit
+		if link.MaxVisits != nil && !errors.Is(err, models.ErrVisitNotLogged) {
+			renderPage(w, r, PageError, http.StatusInternalServerError, PageData{
+				Slug:  slug,
+				Error: "The link couldn't be loaded. Please try again later.",
+			})
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	http.Redirect(w, r, destination, statusCode)
+}
+
+// visitDetails collects the details of a visit from the request
+func visitDetails(r *http.Request, slug string) models.VisitDetails {
+	return models.VisitDetails{
+		Slug:      slug,
+		UserAgent: r.Header.Get("User-Agent"),
+		IPAddress: GetClientIP(r),
+		Referrer:  r.Header.Get("Referer"),
+		Source:    visitSource(r),
+	}
+}
+
+// visitSourcePattern matches the attribution labels that are recorded, the column only holds short ASCII labels
+var visitSourcePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,50}$`)
+
+// visitSource returns the attribution of a visit from the src query parameter, such as "qr" for QR code scans.
+// Values that aren't short labels are ignored, so that they can't make recording the visit fail.
+func visitSource(r *http.Request) string {
+	source := r.URL.Query().Get(lib.QR_SOURCE_PARAM)
+	if !visitSourcePattern.MatchString(source) {
+		return ""
+	}
+	return source
+}
+
+// isLinkAvailable reports whether a link still redirects to its destination.
+// Expired and used up links are unavailable even before the expiration worker deactivates them.
+func isLinkAvailable(link models.Link) bool {
+	if !link.IsActive {
+		return false
+	}
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now()) {
+		return false
+	}
+	if link.MaxVisits != nil && link.Visits >= *link.MaxVisits {
+		return false
+	}
+	return true
+}
+
+// fallbackURL returns where visitors of an unavailable link are sent: the link's own fallback,
+// then its key's fallback, then the server default. It returns an empty string if none is set.
+func fallbackURL(db *gorm.DB, env *utils.Env, link models.Link) string {
+	if link.FallbackURL != nil {
+		return *link.FallbackURL
+	}
+	if key := models.SearchKeyByID(db, link.CreatedBy); key != nil && key.FallbackURL != nil {
+		return *key.FallbackURL
+	}
+	return env.DEFAULT_FALLBACK_URL
+}
+
+// serveFallback sends visitors of an unavailable link to its fallback URL and records a fallback visit.
+// Without a fallback, the expired or disabled page is shown.
+func serveFallback(w http.ResponseWriter, r *http.Request, db *gorm.DB, env *utils.Env, link models.Link, slug string) {
+	destination := fallbackURL(db, env, link)
+	if destination == "" {
+		renderUnavailablePage(w, r, link, slug)
+		return
+	}
+
+	if err := models.RecordFallbackVisit(db, link.ID, visitDetails(r, slug)); err != nil {
+		log.Printf("Error recording fallback visit for '%s': %v", slug, err)
+	}
+
+	// the link may be reactivated or extended, so fallback redirects are never cached
+	w.Header().Set("Cache-Control", redirectCacheControl(http.StatusFound))
+	http.Redirect(w, r, destination, http.StatusFound)
+}
+
+// renderUnavailablePage tells visitors why a link without a fallback doesn't redirect anymore
+func renderUnavailablePage(w http.ResponseWriter, r *http.Request, link models.Link, slug string) {
+	expired := (link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now())) ||
+		(link.MaxVisits != nil && link.Visits >= *link.MaxVisits)
+
+	page := PageDisabled
+	if expired || link.IsActive {
+		// active but unavailable links have been used up by a concurrent visit
+		page = PageExpired
+	}
+	renderPage(w, r, page, http.StatusGone, PageData{Slug: slug})
+}
+
+// hasStarted reports whether a link is live, i.e. it has no scheduled start or its start time has passed
+func hasStarted(link models.Link) bool {
+	return link.StartsAt == nil || !time.Now().Before(*link.StartsAt)
+}
+
+// serveNotStarted sends visitors of a link that isn't live yet to its pre-launch URL, or shows the "coming soon" page
+func serveNotStarted(w http.ResponseWriter, r *http.Request, link models.Link, slug string) {
+	if link.PrelaunchURL != nil {
+		w.Header().Set("Cache-Control", redirectCacheControl(http.StatusFound))
+		http.Redirect(w, r, *link.PrelaunchURL, http.StatusFound)
+		return
+	}
+	renderComingSoonPage(w, r, slug, *link.StartsAt)
+}
+
+// redirectStatusCode returns the status code a link redirects with, falling back to its domain's and then the server default
+func redirectStatusCode(link models.Link, domain *models.Domain, env *utils.Env) int {
+	if lib.REDIRECT_STATUS_CODES[link.RedirectType] {
+		return link.RedirectType
+	}
+	if domain != nil && lib.REDIRECT_STATUS_CODES[domain.DefaultRedirectType] {
+		return domain.DefaultRedirectType
+	}
+	return env.DEFAULT_REDIRECT_CODE
+}
+
+// redirectCacheControl returns the Cache-Control header matching a redirect status code.
+// Permanent redirects are only cached for a day so that destination changes still reach returning visitors,
+// temporary redirects are never cached so that every click goes through visit tracking.
+func redirectCacheControl(statusCode int) string {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		return "public, max-age=86400"
+	default:
+		return "no-cache, no-store, must-revalidate"
+	}
+}
+
+// resolveRedirectLink finds the link for a slug, which may also be one of the link's aliases.
+// If the slug is the old slug of a renamed link, the link is resolved through its historical slug, which is returned as well.
+func resolveRedirectLink(db *gorm.DB, scope models.SlugScope, slug string) (*models.Link, *models.HistoricalSlug, error) {
+	link, err := RetrieveRedirectURL(db, scope, slug)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return link, nil, err
+	}
+
+	alias, err := models.FindLinkAlias(db, scope, slug)
+	if err == nil {
+		link, err = RetrieveRedirectURLByID(db, alias.LinkID)
+		return link, nil, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	historicalSlug, err := models.FindHistoricalSlug(db, scope, slug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link, err = RetrieveRedirectURLByID(db, historicalSlug.LinkID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return link, historicalSlug, nil
+}
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRedirectToLinkVisitRecording(t *testing.T) {
+	maxVisits := 1
+	dbDown := errors.New("connection reset")
+
+	tests := []struct {
+		name      string
+		maxVisits *int
+		// update and insert answer the visit count UPDATE and the visit record INSERT
+		update           testResult
+		insert           testResult
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:             "visit recorded",
+			maxVisits:        &maxVisits,
+			update:           testResult{RowsAffected: 1},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/docs",
+		},
+		{
+			name:           "visit of a limited link not counted",
+			maxVisits:      &maxVisits,
+			update:         testResult{Err: dbDown},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:             "visit of an unlimited link not counted",
+			update:           testResult{Err: dbDown},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/docs",
+		},
+		{
+			name:             "visit of a limited link counted but not logged",
+			maxVisits:        &maxVisits,
+			update:           testResult{RowsAffected: 1},
+			insert:           testResult{Err: dbDown},
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/docs",
+		},
+		{
+			name:           "limited link used up",
+			maxVisits:      &maxVisits,
+			update:         testResult{RowsAffected: 0},
+			expectedStatus: http.StatusGone,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, _ := newTestDB(t, func(query string, args []driver.Value) testResult {
+				switch {
+				case strings.HasPrefix(query, `UPDATE "links"`):
+					return test.update
+				case strings.HasPrefix(query, `INSERT INTO "link_visits"`):
+					return test.insert
+				}
+				// the link has no rules, variants or fallbacks
+				return testResult{}
+			})
+
+			link := models.Link{ID: uuid.New(), RedirectTo: "https://example.com/docs", IsActive: true, MaxVisits: test.maxVisits}
+			recorder := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			redirectToLink(recorder, r, db, &utils.Env{}, link, "docs", "", http.StatusFound)
+
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("status = %d, expected %d", recorder.Code, test.expectedStatus)
+			}
+			if location := recorder.Header().Get("Location"); location != test.expectedLocation {
+				t.Errorf("Location = %q, expected %q", location, test.expectedLocation)
+			}
+		})
+	}
+}
+
+// redirectTestData are the rows the test database of the redirect router holds, all on the default domain and outside namespaces
+type redirectTestData struct {
+	links           []models.Link
+	aliases         []models.LinkAlias
+	historicalSlugs []models.HistoricalSlug
+	// visits collects the values of the visit records that are created, if set
+	visits *[][]driver.Value
+}
+
+// handle answers the lookups of the redirect router, visits are always counted
+func (data redirectTestData) handle(query string, args []driver.Value) testResult {
+	switch {
+	case strings.HasPrefix(query, `SELECT * FROM "links" WHERE shortened = `):
+		for _, link := range data.links {
+			if link.Shortened == args[0] {
+				return linkRows(link)
+			}
+		}
+	case strings.HasPrefix(query, `SELECT * FROM "links" WHERE id = `):
+		for _, link := range data.links {
+			if link.ID.String() == args[0] {
+				return linkRows(link)
+			}
+		}
+	case strings.HasPrefix(query, `SELECT * FROM "link_aliases"`):
+		for _, alias := range data.aliases {
+			if alias.Slug == args[0] {
+				return testResult{Columns: []string{"id", "link_id", "slug"}, Rows: [][]driver.Value{
+					{alias.ID.String(), alias.LinkID.String(), alias.Slug},
+				}}
+			}
+		}
+	case strings.HasPrefix(query, `SELECT * FROM "historical_slugs"`):
+		for _, historicalSlug := range data.historicalSlugs {
+			if historicalSlug.Slug == args[0] {
+				return testResult{Columns: []string{"id", "link_id", "slug", "redirect_to_current"}, Rows: [][]driver.Value{
+					{historicalSlug.ID.String(), historicalSlug.LinkID.String(), historicalSlug.Slug, historicalSlug.RedirectToCurrent},
+				}}
+			}
+		}
+	case strings.HasPrefix(query, `UPDATE "links"`):
+		return testResult{RowsAffected: 1}
+	case strings.HasPrefix(query, `INSERT INTO "link_visits"`):
+		if data.visits != nil {
+			*data.visits = append(*data.visits, args)
+		}
+	}
+	// no domains, namespaces, keys, rules or variants
+	return testResult{}
+}
+
+// linkRows answers a query for links with the given links
+func linkRows(links ...models.Link) testResult {
+	result := testResult{Columns: []string{"id", "shortened", "redirect_to", "created_at", "created_by", "visits", "is_active",
+		"redirect_type", "max_visits", "expires_at", "starts_at", "prelaunch_url", "fallback_url", "path_mode"}}
+	for _, link := range links {
+		row := []driver.Value{link.ID.String(), link.Shortened, link.RedirectTo, link.CreatedAt, link.CreatedBy.String(),
+			int64(link.Visits), link.IsActive, int64(link.RedirectType), nil, nil, nil, nil, nil, string(link.PathMode)}
+		if link.MaxVisits != nil {
+			row[8] = int64(*link.MaxVisits)
+		}
+		if link.ExpiresAt != nil {
+			row[9] = *link.ExpiresAt
+		}
+		if link.StartsAt != nil {
+			row[10] = *link.StartsAt
+		}
+		if link.PrelaunchURL != nil {
+			row[11] = *link.PrelaunchURL
+		}
+		if link.FallbackURL != nil {
+			row[12] = *link.FallbackURL
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
+
+// serveRedirect sends a GET request through the redirect router's handler, the test database tells what it was asked
+func serveRedirect(t *testing.T, data redirectTestData, env *utils.Env, target string, accept string) (*httptest.ResponseRecorder, *testDB) {
+	t.Helper()
+	db, testDB := newTestDB(t, data.handle)
+	database.SetDB(db)
+	t.Cleanup(func() { database.SetDB(nil) })
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	redirectHandler(env)(recorder, r)
+	return recorder, testDB
+}
+
+func TestRedirectPreviewMarker(t *testing.T) {
+	data := redirectTestData{links: []models.Link{
+		{ID: uuid.New(), Shortened: "search", RedirectTo: "https://example.com/search", IsActive: true, PathMode: models.LinkPathPassthrough},
+	}}
+
+	tests := []struct {
+		name             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"slug", "/search", http.StatusFound, "https://example.com/search"},
+		{"preview", "/search+", http.StatusOK, ""},
+		{"preview with trailing slash", "/search+/", http.StatusOK, ""},
+		{"rest ending in +", "/search/c++", http.StatusFound, "https://example.com/search/c++"},
+		{"nested rest ending in +", "/search/lang/c+", http.StatusFound, "https://example.com/search/lang/c+"},
+		{"preview of an unknown slug", "/docs+", http.StatusNotFound, ""},
+	}
+
+	env := &utils.Env{DEFAULT_REDIRECT_CODE: http.StatusFound}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, _ := serveRedirect(t, data, env, test.target, "application/json")
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("status = %d, expected %d: %s", recorder.Code, test.expectedStatus, recorder.Body)
+			}
+			if location := recorder.Header().Get("Location"); location != test.expectedLocation {
+				t.Errorf("Location = %q, expected %q", location, test.expectedLocation)
+			}
+		})
+	}
+}
+
+func TestRedirectHistoricalSlug(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Hour)
+	renamed := models.Link{ID: uuid.New(), Shortened: "q3", RedirectTo: "https://example.com/report", IsActive: true}
+	permanent := models.Link{ID: uuid.New(), Shortened: "launch", RedirectTo: "https://example.com/launch", IsActive: true, RedirectType: http.StatusMovedPermanently}
+	expired := models.Link{ID: uuid.New(), Shortened: "sale", RedirectTo: "https://example.com/sale", IsActive: true, ExpiresAt: &expiredAt}
+	data := redirectTestData{
+		links: []models.Link{renamed, permanent, expired},
+		historicalSlugs: []models.HistoricalSlug{
+			{ID: uuid.New(), LinkID: renamed.ID, Slug: "q3-report"},
+			{ID: uuid.New(), LinkID: renamed.ID, Slug: "report", RedirectToCurrent: true},
+			{ID: uuid.New(), LinkID: permanent.ID, Slug: "launch-2024", RedirectToCurrent: true},
+			{ID: uuid.New(), LinkID: expired.ID, Slug: "summer-sale", RedirectToCurrent: true},
+		},
+	}
+
+	tests := []struct {
+		name                 string
+		target               string
+		expectedStatus       int
+		expectedLocation     string
+		expectedCacheControl string
+		// counted tells whether the visit goes through visit tracking
+		counted bool
+	}{
+		{"old slug to the destination", "/q3-report", http.StatusFound, "https://example.com/report", "no-cache, no-store, must-revalidate", true},
+		{"old slug to the current slug", "/report", http.StatusFound, "/q3", "no-cache, no-store, must-revalidate", false},
+		{"old slug keeps the query", "/report?utm_source=mail", http.StatusFound, "/q3?utm_source=mail", "no-cache, no-store, must-revalidate", false},
+		{"old slug with the link's redirect type", "/launch-2024", http.StatusMovedPermanently, "/launch", "public, max-age=86400", false},
+		{"old slug of an expired link", "/summer-sale", http.StatusGone, "", "no-store", false},
+		{"unknown slug", "/q4-report", http.StatusNotFound, "", "no-store", false},
+	}
+
+	env := &utils.Env{DEFAULT_REDIRECT_CODE: http.StatusFound}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, testDB := serveRedirect(t, data, env, test.target, "")
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("status = %d, expected %d", recorder.Code, test.expectedStatus)
+			}
+			if location := recorder.Header().Get("Location"); location != test.expectedLocation {
+				t.Errorf("Location = %q, expected %q", location, test.expectedLocation)
+			}
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != test.expectedCacheControl {
+				t.Errorf("Cache-Control = %q, expected %q", cacheControl, test.expectedCacheControl)
+			}
+			if counted := testDB.Executed(`UPDATE "links"`); counted != test.counted {
+				t.Errorf("visit counted = %t, expected %t", counted, test.counted)
+			}
+		})
+	}
+}
+
+func TestRedirectAlias(t *testing.T) {
+	report := models.Link{ID: uuid.New(), Shortened: "q3-report", RedirectTo: "https://example.com/report", IsActive: true}
+	archived := models.Link{ID: uuid.New(), Shortened: "q2-report", RedirectTo: "https://example.com/q2", IsActive: false}
+	var visits [][]driver.Value
+	data := redirectTestData{
+		links: []models.Link{report, archived},
+		aliases: []models.LinkAlias{
+			{ID: uuid.New(), LinkID: report.ID, Slug: "q3"},
+			{ID: uuid.New(), LinkID: report.ID, Slug: "report"},
+			{ID: uuid.New(), LinkID: archived.ID, Slug: "q2"},
+		},
+		visits: &visits,
+	}
+
+	tests := []struct {
+		name             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		// expectedVisitSlug is the slug the visit is recorded with, empty if no visit is recorded
+		expectedVisitSlug string
+	}{
+		{"slug", "/q3-report", http.StatusFound, "https://example.com/report", "q3-report"},
+		{"alias", "/q3", http.StatusFound, "https://example.com/report", "q3"},
+		{"second alias", "/report", http.StatusFound, "https://example.com/report", "report"},
+		{"alias of a disabled link", "/q2", http.StatusGone, "", ""},
+		{"alias with a path", "/q3/summary", http.StatusNotFound, "", ""},
+	}
+
+	env := &utils.Env{DEFAULT_REDIRECT_CODE: http.StatusFound}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			visits = nil
+			recorder, _ := serveRedirect(t, data, env, test.target, "")
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("status = %d, expected %d", recorder.Code, test.expectedStatus)
+			}
+			if location := recorder.Header().Get("Location"); location != test.expectedLocation {
+				t.Errorf("Location = %q, expected %q", location, test.expectedLocation)
+			}
+
+			if test.expectedVisitSlug == "" {
+				if len(visits) > 0 {
+					t.Errorf("recorded %d visits, expected none", len(visits))
+				}
+				return
+			}
+			if len(visits) != 1 {
+				t.Fatalf("recorded %d visits, expected one", len(visits))
+			}
+			found := false
+			for _, value := range visits[0] {
+				found = found || value == test.expectedVisitSlug
+			}
+			if !found {
+				t.Errorf("visit values = %v, expected the slug %q", visits[0], test.expectedVisitSlug)
+			}
+		})
+	}
+}
+
+func TestRedirectStatusCode(t *testing.T) {
+	env := &utils.Env{DEFAULT_REDIRECT_CODE: http.StatusMovedPermanently}
+	temporaryDomain := &models.Domain{DefaultRedirectType: http.StatusTemporaryRedirect}
+
+	tests := []struct {
+		name                 string
+		redirectType         int
+		domain               *models.Domain
+		expectedStatus       int
+		expectedCacheControl string
+	}{
+		{"server default", 0, nil, http.StatusMovedPermanently, "public, max-age=86400"},
+		{"domain default", 0, temporaryDomain, http.StatusTemporaryRedirect, "no-cache, no-store, must-revalidate"},
+		{"domain without a default", 0, &models.Domain{}, http.StatusMovedPermanently, "public, max-age=86400"},
+		{"link 302", http.StatusFound, temporaryDomain, http.StatusFound, "no-cache, no-store, must-revalidate"},
+		{"link 308", http.StatusPermanentRedirect, temporaryDomain, http.StatusPermanentRedirect, "public, max-age=86400"},
+		{"unsupported link type", http.StatusSeeOther, nil, http.StatusMovedPermanently, "public, max-age=86400"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			link := models.Link{RedirectType: test.redirectType}
+			statusCode := redirectStatusCode(link, test.domain, env)
+			if statusCode != test.expectedStatus {
+				t.Errorf("redirectStatusCode = %d, expected %d", statusCode, test.expectedStatus)
+			}
+			if cacheControl := redirectCacheControl(statusCode); cacheControl != test.expectedCacheControl {
+				t.Errorf("redirectCacheControl(%d) = %q, expected %q", statusCode, cacheControl, test.expectedCacheControl)
+			}
+		})
+	}
+}
+
+func TestRedirectCacheControl(t *testing.T) {
+	data := redirectTestData{links: []models.Link{
+		{ID: uuid.New(), Shortened: "docs", RedirectTo: "https://example.com/docs", IsActive: true},
+		{ID: uuid.New(), Shortened: "blog", RedirectTo: "https://example.com/blog", IsActive: true, RedirectType: http.StatusTemporaryRedirect},
+		{ID: uuid.New(), Shortened: "old", RedirectTo: "https://example.com/old", IsActive: false},
+	}}
+
+	tests := []struct {
+		name                 string
+		target               string
+		expectedStatus       int
+		expectedCacheControl string
+	}{
+		{"server default", "/docs", http.StatusPermanentRedirect, "public, max-age=86400"},
+		{"link type", "/blog", http.StatusTemporaryRedirect, "no-cache, no-store, must-revalidate"},
+		{"disabled link", "/old", http.StatusGone, "no-store"},
+	}
+
+	env := &utils.Env{DEFAULT_REDIRECT_CODE: http.StatusPermanentRedirect}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, _ := serveRedirect(t, data, env, test.target, "")
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("status = %d, expected %d", recorder.Code, test.expectedStatus)
+			}
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != test.expectedCacheControl {
+				t.Errorf("Cache-Control = %q, expected %q", cacheControl, test.expectedCacheControl)
+			}
+		})
+	}
+}
+
+func TestRedirectNotStarted(t *testing.T) {
+	startsAt := time.Now().Add(time.Hour)
+	startedAt := time.Now().Add(-time.Hour)
+	prelaunchURL := "https://example.com/waitlist"
+	data := redirectTestData{links: []models.Link{
+		{ID: uuid.New(), Shortened: "launch", RedirectTo: "https://example.com/launch", IsActive: true, StartsAt: &startsAt},
+		{ID: uuid.New(), Shortened: "beta", RedirectTo: "https://example.com/beta", IsActive: true, StartsAt: &startsAt, PrelaunchURL: &prelaunchURL},
+		{ID: uuid.New(), Shortened: "live", RedirectTo: "https://example.com/live", IsActive: true, StartsAt: &startedAt},
+	}}
+
+	tests := []struct {
+		name             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		// expectedCode is the code of the JSON body, if any
+		expectedCode Page
+		counted      bool
+	}{
+		{"coming soon", "/launch", http.StatusServiceUnavailable, "", PageComingSoon, false},
+		{"pre-launch URL", "/beta", http.StatusFound, prelaunchURL, "", false},
+		{"started", "/live", http.StatusFound, "https://example.com/live", "", true},
+		{"preview before the start", "/launch+", http.StatusServiceUnavailable, "", PageComingSoon, false},
+	}
+
+	env := &utils.Env{DEFAULT_REDIRECT_CODE: http.StatusFound}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, testDB := serveRedirect(t, data, env, test.target, "application/json")
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("status = %d, expected %d", recorder.Code, test.expectedStatus)
+			}
+			if location := recorder.Header().Get("Location"); location != test.expectedLocation {
+				t.Errorf("Location = %q, expected %q", location, test.expectedLocation)
+			}
+			if counted := testDB.Executed(`UPDATE "links"`); counted != test.counted {
+				t.Errorf("visit counted = %t, expected %t", counted, test.counted)
+			}
+			if test.expectedStatus == http.StatusFound && recorder.Header().Get("Cache-Control") != "no-cache, no-store, must-revalidate" {
+				t.Errorf("Cache-Control = %q, expected temporary redirects not to be cached", recorder.Header().Get("Cache-Control"))
+			}
+			if test.expectedCode == "" {
+				return
+			}
+
+			var response RedirectErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			if response.Code != test.expectedCode || response.StartsAt == nil || !response.StartsAt.Equal(startsAt) {
+				t.Errorf("response = %+v, expected code %q starting at %v", response, test.expectedCode, startsAt)
+			}
+			retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+			if err != nil || retryAfter < 3500 || retryAfter > 3601 {
+				t.Errorf("Retry-After = %q, expected about an hour", recorder.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
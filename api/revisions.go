@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RetrieveLinkRevisionsRequest struct {
+	Shortened string `json:"shortened"`
+}
+
+// created_by: The key that made the change, the nil UUID for changes the server made itself, such as deactivating expired links
+type LinkRevisionResponse struct {
+	Number     int        `json:"number"`
+	RedirectTo string     `json:"redirect_to"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	IsActive   bool       `json:"is_active"`
+	CreatedBy  uuid.UUID  `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type RetrieveLinkRevisionsResponse struct {
+	Message   string                 `json:"message"`
+	Revisions []LinkRevisionResponse `json:"revisions"`
+}
+
+// convert models.LinkRevision to LinkRevisionResponse
+func ToLinkRevisionResponse(rev models.LinkRevision) LinkRevisionResponse {
+	return LinkRevisionResponse{
+		Number:     rev.Number,
+		RedirectTo: rev.RedirectTo,
+		ExpiresAt:  rev.ExpiresAt,
+		IsActive:   rev.IsActive,
+		CreatedBy:  rev.CreatedBy,
+		CreatedAt:  rev.CreatedAt,
+	}
+}
+
+// RetrieveLinkRevisionsHandler lists the revision history of a link.
+// @Summary Retrieve link revisions
+// @Description Lists every recorded change to a link's destination, expiry or active flag, newest first.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RetrieveLinkRevisionsRequest true "Link revisions request"
+// @Success 200 {object} RetrieveLinkRevisionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/revisions [post]
+func RetrieveLinkRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RetrieveLinkRevisionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	revisions, err := models.RetrieveLinkRevisions(database.GetDB(), link.ID)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Database Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	responseRevisions := make([]LinkRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		responseRevisions = append(responseRevisions, ToLinkRevisionResponse(revision))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetrieveLinkRevisionsResponse{
+		Message:   "Revisions retrieved successfully",
+		Revisions: responseRevisions,
+	})
+}
+
+type RollbackLinkRequest struct {
+	Shortened string `json:"shortened"`
+	Revision  int    `json:"revision"`
+}
+
+// RollbackLinkHandler restores a link's destination, expiry and active flag from an earlier revision.
+// @Summary Roll back a link to a revision
+// @Description Restores a link's destination, expiry and active flag from an earlier revision. The rollback itself is recorded as a new revision.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RollbackLinkRequest true "Link rollback request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/rollback [post]
+func RollbackLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RollbackLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	db := database.GetDB()
+
+	revision, err := models.RetrieveLinkRevision(db, link.ID, request.Revision)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Revision not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Shortened: %s, Revision: %d", request.Shortened, request.Revision),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	previous := *link
+	link.RedirectTo = revision.RedirectTo
+	link.ExpiresAt = revision.ExpiresAt
+	link.IsActive = revision.IsActive
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := models.SaveLinkChanges(tx, previous, link); err != nil {
+			return err
+		}
+		return models.RecordLinkRevision(tx, previous, *link, ctxValues.KeyID)
+	})
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to roll back link",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionLinkRollback, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, models.LinkAuditSnapshot(previous), models.LinkAuditSnapshot(*link))
+}
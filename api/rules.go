@@ -0,0 +1,333 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// platforms that rules can match on
+var rulePlatforms = map[string]bool{
+	"ios": true, "android": true, "windows": true, "macos": true, "linux": true,
+}
+
+// ruleVisitor holds the request details that link rules are matched against
+type ruleVisitor struct {
+	Platform string
+	Language string
+	Country  string
+	Time     time.Time
+}
+
+// detectPlatform returns the operating system of a User-Agent, or an empty string if it isn't recognized
+func detectPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	// iOS user agents also mention "like Mac OS X", so they are checked first
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return "ios"
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "windows"):
+		return "windows"
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return "macos"
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return "linux"
+	default:
+		return ""
+	}
+}
+
+// preferredLanguage returns the language tag with the highest quality in an Accept-Language header, lowercased
+func preferredLanguage(acceptLanguage string) string {
+	type weightedTag struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []weightedTag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		tags = append(tags, weightedTag{tag: tag, quality: quality})
+	}
+
+	if len(tags) == 0 {
+		return ""
+	}
+
+	// stable, so that the header order decides between equal qualities
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+	return tags[0].tag
+}
+
+// languageMatches checks if a visitor's language matches a rule language.
+// A rule for "de" matches "de", "de-at" and "de-ch", a rule for "de-at" only matches "de-at".
+func languageMatches(ruleLanguage string, visitorLanguage string) bool {
+	ruleLanguage = strings.ToLower(ruleLanguage)
+	return visitorLanguage == ruleLanguage || strings.HasPrefix(visitorLanguage, ruleLanguage+"-")
+}
+
+// ruleMatches checks if every condition of a rule matches the visitor
+func ruleMatches(rule models.LinkRule, visitor ruleVisitor) bool {
+	if rule.Platform != nil && *rule.Platform != visitor.Platform {
+		return false
+	}
+	if rule.Language != nil && !languageMatches(*rule.Language, visitor.Language) {
+		return false
+	}
+	if rule.Country != nil && !strings.EqualFold(*rule.Country, visitor.Country) {
+		return false
+	}
+	if rule.ActiveFrom != nil && visitor.Time.Before(*rule.ActiveFrom) {
+		return false
+	}
+	if rule.ActiveTo != nil && !visitor.Time.Before(*rule.ActiveTo) {
+		return false
+	}
+	return true
+}
+
+// matchLinkRule returns the first rule that matches the visitor, or nil if none does
+func matchLinkRule(rules []models.LinkRule, visitor ruleVisitor) *models.LinkRule {
+	for i := range rules {
+		if ruleMatches(rules[i], visitor) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// rulesNeedCountry reports whether any rule matches on country, so that GeoIP lookups are only done when needed
+func rulesNeedCountry(rules []models.LinkRule) bool {
+	for _, rule := range rules {
+		if rule.Country != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newRuleVisitor collects the details of the request that rules are matched against
+func newRuleVisitor(r *http.Request, rules []models.LinkRule) ruleVisitor {
+	visitor := ruleVisitor{
+		Platform: detectPlatform(r.Header.Get("User-Agent")),
+		Language: preferredLanguage(r.Header.Get("Accept-Language")),
+		Time:     time.Now(),
+	}
+	if rulesNeedCountry(rules) {
+		visitor.Country = lookupCountry(GetClientIP(r))
+	}
+	return visitor
+}
+
+// platform: One of ios, android, windows, macos or linux
+// language: A language tag such as "de" or "pt-br", matched against the visitor's preferred language
+// country: An ISO 3166-1 alpha-2 country code, matched using the GeoIP database
+// active_from, active_to: The time window in which the rule applies
+// Conditions that are left empty match every visit, at least one has to be set.
+type LinkRuleRequest struct {
+	Platform   string     `json:"platform,omitempty"`
+	Language   string     `json:"language,omitempty"`
+	Country    string     `json:"country,omitempty"`
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	ActiveTo   *time.Time `json:"active_to,omitempty"`
+	RedirectTo string     `json:"redirect_to"`
+}
+
+// rules: The complete ordered list of rules, replacing the current one. An empty list removes all rules
+type SetLinkRulesRequest struct {
+	Shortened string            `json:"shortened"`
+	Rules     []LinkRuleRequest `json:"rules"`
+}
+
+type LinkRuleResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Position   int        `json:"position"`
+	Platform   *string    `json:"platform,omitempty"`
+	Language   *string    `json:"language,omitempty"`
+	Country    *string    `json:"country,omitempty"`
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+	ActiveTo   *time.Time `json:"active_to,omitempty"`
+	RedirectTo string     `json:"redirect_to"`
+}
+
+// convert []models.LinkRule to []LinkRuleResponse
+func ToLinkRuleResponses(rules []models.LinkRule) []LinkRuleResponse {
+	responses := make([]LinkRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		responses = append(responses, LinkRuleResponse{
+			ID:         rule.ID,
+			Position:   rule.Position,
+			Platform:   rule.Platform,
+			Language:   rule.Language,
+			Country:    rule.Country,
+			ActiveFrom: rule.ActiveFrom,
+			ActiveTo:   rule.ActiveTo,
+			RedirectTo: rule.RedirectTo,
+		})
+	}
+	return responses
+}
+
+// buildLinkRule validates a rule request and converts it to a models.LinkRule
+func buildLinkRule(request LinkRuleRequest) (models.LinkRule, error) {
+	var rule models.LinkRule
+
+	if request.Platform == "" && request.Language == "" && request.Country == "" &&
+		request.ActiveFrom == nil && request.ActiveTo == nil {
+		return rule, errors.New("at least one condition is required")
+	}
+
+	if request.Platform != "" {
+		platform := strings.ToLower(request.Platform)
+		if !rulePlatforms[platform] {
+			return rule, errors.New("platform must be one of ios, android, windows, macos or linux")
+		}
+		rule.Platform = &platform
+	}
+
+	if request.Language != "" {
+		language := strings.ToLower(request.Language)
+		if len(language) > 35 || strings.ContainsAny(language, ",; ") {
+			return rule, errors.New("language must be a single language tag such as \"de\" or \"pt-br\"")
+		}
+		rule.Language = &language
+	}
+
+	if request.Country != "" {
+		country := strings.ToUpper(request.Country)
+		if len(country) != 2 || !isAlphanumeric(country) {
+			return rule, errors.New("country must be an ISO 3166-1 alpha-2 code such as \"DE\"")
+		}
+		rule.Country = &country
+	}
+
+	if request.ActiveFrom != nil && request.ActiveTo != nil && !request.ActiveFrom.Before(*request.ActiveTo) {
+		return rule, errors.New("active_from must be before active_to")
+	}
+	rule.ActiveFrom = request.ActiveFrom
+	rule.ActiveTo = request.ActiveTo
+
+	if request.RedirectTo == "" {
+		return rule, errors.New("redirect_to is required")
+	}
+	redirectTo, err := validateAndNormalizeURL(request.RedirectTo)
+	if err != nil {
+		return rule, fmt.Errorf("invalid redirect_to: %v", err)
+	}
+	rule.RedirectTo = redirectTo
+
+	return rule, nil
+}
+
+// SetLinkRulesHandler replaces the conditional redirect rules of a link.
+// @Summary Set the redirect rules of a link
+// @Description Replaces the ordered list of rules of a link. When a link is visited, the first rule whose conditions (platform, language, country, time window) all match picks the destination, otherwise the link's redirect_to is used.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body SetLinkRulesRequest true "Link rules request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/set-rules [post]
+func SetLinkRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request SetLinkRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	rules := make([]models.LinkRule, 0, len(request.Rules))
+	for i, ruleRequest := range request.Rules {
+		rule, err := buildLinkRule(ruleRequest)
+		if err != nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   fmt.Sprintf("Invalid rule %d: %v", i+1, err),
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		rules = append(rules, rule)
+	}
+
+	rulesBefore := ToLinkRuleResponses(link.Rules)
+
+	rules, err := models.ReplaceLinkRules(database.GetDB(), link.ID, rules)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to save rules",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	link.Rules = rules
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionLinkSetRules, models.AuditTargetLink,
+		link.ID.String(), link.Shortened,
+		map[string]interface{}{"rules": rulesBefore}, map[string]interface{}{"rules": ToLinkRuleResponses(rules)})
+}
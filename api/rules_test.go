@@ -0,0 +1,76 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"testing"
+	"time"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15": "ios",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36":                 "android",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36":                "windows",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15":        "macos",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0":      "linux",
+		"curl/8.4.0": "",
+	}
+
+	for userAgent, expected := range tests {
+		if platform := detectPlatform(userAgent); platform != expected {
+			t.Errorf("detectPlatform(%q) = %q, expected %q", userAgent, platform, expected)
+		}
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                             "",
+		"de-DE,de;q=0.9,en;q=0.8":      "de-de",
+		"en;q=0.5, fr;q=0.8":           "fr",
+		"*;q=1, es":                    "es",
+		"en;q=0, pt-BR;q=0.1":          "pt-br",
+		"nl;q=0.7, it;q=0.7, de;q=0.6": "nl",
+	}
+
+	for header, expected := range tests {
+		if language := preferredLanguage(header); language != expected {
+			t.Errorf("preferredLanguage(%q) = %q, expected %q", header, language, expected)
+		}
+	}
+}
+
+func TestMatchLinkRule(t *testing.T) {
+	ios, android, german := "ios", "android", "de"
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	rules := []models.LinkRule{
+		{Position: 1, Platform: &ios, RedirectTo: "https://apps.apple.com"},
+		{Position: 2, Platform: &android, ActiveFrom: &later, RedirectTo: "https://play.google.com"},
+		{Position: 3, Language: &german, RedirectTo: "https://example.com/de"},
+	}
+
+	tests := []struct {
+		name     string
+		visitor  ruleVisitor
+		expected string
+	}{
+		{"first matching rule wins", ruleVisitor{Platform: "ios", Language: "de", Time: now}, "https://apps.apple.com"},
+		{"time window not reached", ruleVisitor{Platform: "android", Language: "en", Time: now}, ""},
+		{"time window reached", ruleVisitor{Platform: "android", Language: "en", Time: later}, "https://play.google.com"},
+		{"language prefix", ruleVisitor{Platform: "windows", Language: "de-at", Time: now}, "https://example.com/de"},
+		{"no match", ruleVisitor{Platform: "windows", Language: "en-us", Time: now}, ""},
+	}
+
+	for _, test := range tests {
+		rule := matchLinkRule(rules, test.visitor)
+		destination := ""
+		if rule != nil {
+			destination = rule.RedirectTo
+		}
+		if destination != test.expected {
+			t.Errorf("%s: matched %q, expected %q", test.name, destination, test.expected)
+		}
+	}
+}
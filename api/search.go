@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchQueryLength = 255
+	maxLinkTitleLength   = 255
+	maxLinkNotesLength   = 5000
+)
+
+// normalizeLinkText trims a link's title or notes, nil is returned for empty text
+func normalizeLinkText(name, text string, maxLength int) (*string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(text) > maxLength {
+		return nil, fmt.Errorf("%s must be at most %d characters long", name, maxLength)
+	}
+	return &text, nil
+}
+
+// query: The text to search for, ignoring case
+// fields: The fields to search: shortened, redirect_to, title, notes or tags. If empty, every field is searched
+// slug_match: How the query matches shortened: substring (default) or prefix
+// sort: created_at (default), visits or last_visited_at
+type SearchLinksRequest struct {
+	Query     string   `json:"query"`
+	Fields    []string `json:"fields,omitempty"`
+	SlugMatch string   `json:"slug_match,omitempty"`
+	ListRequest
+	LinkFilterRequest
+}
+
+type SearchLinksResponse struct {
+	Message string                 `json:"message"`
+	Links   []RetrieveLinkResponse `json:"links"`
+	models.PageInfo
+}
+
+var linkSearchFields = map[models.LinkSearchField]bool{
+	models.LinkSearchShortened:  true,
+	models.LinkSearchRedirectTo: true,
+	models.LinkSearchTitle:      true,
+	models.LinkSearchNotes:      true,
+	models.LinkSearchTags:       true,
+}
+
+// search validates the search request and converts it to a models.LinkSearch
+func (s SearchLinksRequest) search() (models.LinkSearch, error) {
+	search := models.LinkSearch{Query: strings.TrimSpace(s.Query)}
+	if search.Query == "" {
+		return search, errors.New("query is required")
+	}
+	if utf8.RuneCountInString(search.Query) > maxSearchQueryLength {
+		return search, fmt.Errorf("query must be at most %d characters long", maxSearchQueryLength)
+	}
+
+	switch s.SlugMatch {
+	case "", "substring":
+	case "prefix":
+		search.SlugPrefix = true
+	default:
+		return search, errors.New("slug_match must be substring or prefix")
+	}
+
+	seen := make(map[models.LinkSearchField]bool, len(s.Fields))
+	for _, name := range s.Fields {
+		field := models.LinkSearchField(strings.ToLower(strings.TrimSpace(name)))
+		if !linkSearchFields[field] {
+			return search, fmt.Errorf("invalid search field '%s': fields are shortened, redirect_to, title, notes or tags", name)
+		}
+		if !seen[field] {
+			seen[field] = true
+			search.Fields = append(search.Fields, field)
+		}
+	}
+
+	return search, nil
+}
+
+// SearchLinksHandler searches links by their slug, destination and metadata.
+// @Summary Search links
+// @Description Finds links whose shortened URL, destination, title, notes or tag names contain the query, ignoring case.
+// @Description Admins search every link, other keys only the links they created. Trashed links aren't searched. Pass next_cursor as cursor to fetch the next page.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body SearchLinksRequest true "Link search request"
+// @Success 200 {object} SearchLinksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/search [post]
+func SearchLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		config := ErrorResponseConfig{
+			Status:    http.StatusMethodNotAllowed,
+			Message:   "Method not allowed",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request SearchLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	db := database.GetDB()
+	if db == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   lib.ERRORS.Database,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	search, err := request.search()
+	var filter models.LinkFilter
+	if err == nil {
+		filter, err = request.LinkFilterRequest.filter()
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	// non-admins can only find their own links
+	if !ctxValues.IsAdmin {
+		keyID := ctxValues.KeyID
+		search.CreatedBy = &keyID
+	}
+
+	links, page, err := models.SearchLinks(db, search, filter, request.ListRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	response := SearchLinksResponse{
+		Message:  "Links searched successfully",
+		Links:    ToRetrieveLinkResponses(links),
+		PageInfo: page,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Server Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+}
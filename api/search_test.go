@@ -0,0 +1,47 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"reflect"
+	"testing"
+)
+
+func TestSearchLinksRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  SearchLinksRequest
+		expected models.LinkSearch
+		valid    bool
+	}{
+		{"trimmed query", SearchLinksRequest{Query: "  launch "}, models.LinkSearch{Query: "launch"}, true},
+		{"blank query", SearchLinksRequest{Query: "  "}, models.LinkSearch{}, false},
+		{"slug prefix", SearchLinksRequest{Query: "q2", SlugMatch: "prefix"}, models.LinkSearch{Query: "q2", SlugPrefix: true}, true},
+		{"unknown slug match", SearchLinksRequest{Query: "q2", SlugMatch: "exact"}, models.LinkSearch{}, false},
+		{"fields deduplicated", SearchLinksRequest{Query: "q2", Fields: []string{"Title", "tags", "title"}},
+			models.LinkSearch{Query: "q2", Fields: []models.LinkSearchField{models.LinkSearchTitle, models.LinkSearchTags}}, true},
+		{"unknown field", SearchLinksRequest{Query: "q2", Fields: []string{"password"}}, models.LinkSearch{}, false},
+	}
+
+	for _, test := range tests {
+		search, err := test.request.search()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: error = %v, expected valid = %v", test.name, err, test.valid)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(search, test.expected) {
+			t.Errorf("%s: search = %+v, expected %+v", test.name, search, test.expected)
+		}
+	}
+}
+
+func TestNormalizeLinkText(t *testing.T) {
+	if text, err := normalizeLinkText("title", "  ", maxLinkTitleLength); text != nil || err != nil {
+		t.Errorf("blank title = %v, %v, expected nil", text, err)
+	}
+	if text, err := normalizeLinkText("title", " Spring launch ", maxLinkTitleLength); err != nil || text == nil || *text != "Spring launch" {
+		t.Errorf("title = %v, %v, expected \"Spring launch\"", text, err)
+	}
+	if _, err := normalizeLinkText("title", "ééééé", 4); err == nil {
+		t.Error("normalizeLinkText accepted a title longer than the limit")
+	}
+}
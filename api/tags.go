@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/models"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxLinkTags      = 20
+	maxFolderLength  = 255
+	maxFolderSegment = 100
+)
+
+var (
+	tagNamePattern       = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+	folderSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9 _.-]+$`)
+)
+
+// normalizeTags lowercases, deduplicates and sorts tag names.
+// Tags are 1 to 50 letters, digits, "-" or "_", starting with a letter or digit.
+func normalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag := strings.ToLower(strings.TrimSpace(name))
+		if !tagNamePattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag '%s': tags are 1 to 50 letters, digits, '-' or '_'", name)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxLinkTags {
+		return nil, fmt.Errorf("a link can have at most %d tags", maxLinkTags)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// normalizeFolder trims a "/" separated folder path such as "marketing/2024", nil is returned for an empty path
+func normalizeFolder(folder string) (*string, error) {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		return nil, nil
+	}
+	if len(folder) > maxFolderLength {
+		return nil, fmt.Errorf("folder must be at most %d characters long", maxFolderLength)
+	}
+
+	segments := strings.Split(folder, "/")
+	for i, segment := range segments {
+		segments[i] = strings.TrimSpace(segment)
+		if segments[i] == "" || len(segments[i]) > maxFolderSegment || !folderSegmentPattern.MatchString(segments[i]) {
+			return nil, errors.New("folder must be a '/' separated path of letters, digits, spaces, '.', '-' or '_'")
+		}
+	}
+
+	normalized := strings.Join(segments, "/")
+	return &normalized, nil
+}
+
+// tag: Only aggregate this tag, if empty, every tag is aggregated
+// since: Only count visits from this time on
+// until: Only count visits before this time
+type RetrieveTagStatsRequest struct {
+	Tag   string     `json:"tag,omitempty"`
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+type RetrieveTagStatsResponse struct {
+	Message string            `json:"message"`
+	Tags    []models.TagStats `json:"tags"`
+}
+
+// RetrieveTagStatsHandler aggregates the links and visits of each tag.
+// @Summary Retrieve tag analytics
+// @Description Reports how many links carry each tag and how many visits they received together, e.g. how all "spring-campaign" links did.
+// @Description Admins aggregate every link, other keys only the links they created. Trashed links and fallback visits aren't counted.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RetrieveTagStatsRequest true "Tag analytics request"
+// @Success 200 {object} RetrieveTagStatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/tag-stats [post]
+func RetrieveTagStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RetrieveTagStatsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	filter, err := LinkFilterRequest{Tag: request.Tag}.filter()
+	if err == nil && request.Since != nil && request.Until != nil && !request.Since.Before(*request.Until) {
+		err = errors.New("since must be before until")
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	statsFilter := models.TagStatsFilter{Tag: filter.Tag, Since: request.Since, Until: request.Until}
+	if !ctxValues.IsAdmin {
+		keyID := ctxValues.KeyID
+		statsFilter.CreatedBy = &keyID
+	}
+
+	stats, err := models.RetrieveTagStats(database.GetDB(), statsFilter)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Database Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	if stats == nil {
+		stats = []models.TagStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetrieveTagStatsResponse{
+		Message: "Tag analytics retrieved successfully",
+		Tags:    stats,
+	})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		expected []string
+		valid    bool
+	}{
+		{"empty", nil, []string{}, true},
+		{"lowercased and sorted", []string{"Spring-Campaign", "ads"}, []string{"ads", "spring-campaign"}, true},
+		{"deduplicated", []string{"q2", " Q2 ", "q2"}, []string{"q2"}, true},
+		{"underscore", []string{"launch_2024"}, []string{"launch_2024"}, true},
+		{"blank", []string{""}, nil, false},
+		{"leading dash", []string{"-ads"}, nil, false},
+		{"space", []string{"spring campaign"}, nil, false},
+		{"too long", []string{"abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz"}, nil, false},
+	}
+
+	for _, test := range tests {
+		tags, err := normalizeTags(test.tags)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: normalizeTags error = %v, expected valid = %v", test.name, err, test.valid)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(tags, test.expected) {
+			t.Errorf("%s: normalizeTags = %v, expected %v", test.name, tags, test.expected)
+		}
+	}
+}
+
+func TestNormalizeFolder(t *testing.T) {
+	tests := []struct {
+		name     string
+		folder   string
+		expected string
+		valid    bool
+	}{
+		{"empty", "", "", true},
+		{"only slashes", " / ", "", true},
+		{"single", "marketing", "marketing", true},
+		{"nested", "marketing/2024", "marketing/2024", true},
+		{"trimmed", "/Marketing / Q2 launch/", "Marketing/Q2 launch", true},
+		{"empty segment", "marketing//2024", "", false},
+		{"wildcard", "market%", "", false},
+	}
+
+	for _, test := range tests {
+		folder, err := normalizeFolder(test.folder)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: normalizeFolder error = %v, expected valid = %v", test.name, err, test.valid)
+			continue
+		}
+		result := ""
+		if folder != nil {
+			result = *folder
+		}
+		if test.valid && result != test.expected {
+			t.Errorf("%s: normalizeFolder = %q, expected %q", test.name, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testResult is what the test database answers to a statement
+type testResult struct {
+	Columns []string
+	Rows    [][]driver.Value
+	// RowsAffected is reported for statements that don't return rows
+	RowsAffected int64
+	Err          error
+}
+
+// testQueryHandler answers the statements sent to the test database, query is the SQL with $n placeholders
+type testQueryHandler func(query string, args []driver.Value) testResult
+
+// testDB is a database connection whose statements are answered by a handler instead of postgres
+type testDB struct {
+	handle testQueryHandler
+
+	mu         sync.Mutex
+	statements []string
+}
+
+// newTestDB opens a gorm connection on the test database
+func newTestDB(t *testing.T, handle testQueryHandler) (*gorm.DB, *testDB) {
+	t.Helper()
+	testDB := &testDB{handle: handle}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(testDB)}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	return db, testDB
+}
+
+// Statements returns the statements the database received, in order
+func (d *testDB) Statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.statements...)
+}
+
+// Executed reports whether a statement containing fragment was received
+func (d *testDB) Executed(fragment string) bool {
+	for _, statement := range d.Statements() {
+		if strings.Contains(statement, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *testDB) answer(query string, args []driver.NamedValue) testResult {
+	d.mu.Lock()
+	d.statements = append(d.statements, query)
+	d.mu.Unlock()
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return d.handle(query, values)
+}
+
+func (d *testDB) Connect(context.Context) (driver.Conn, error) { return testConn{d}, nil }
+func (d *testDB) Driver() driver.Driver                        { return nil }
+
+type testConn struct{ db *testDB }
+
+func (c testConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements aren't supported by the test database")
+}
+func (c testConn) Close() error              { return nil }
+func (c testConn) Begin() (driver.Tx, error) { return testTx{}, nil }
+
+func (c testConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.answer(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+func (c testConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.answer(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &testRows{columns: result.Columns, rows: result.Rows}, nil
+}
+
+type testTx struct{}
+
+func (testTx) Commit() error   { return nil }
+func (testTx) Rollback() error { return nil }
+
+type testRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"net/http"
+)
+
+// key: The secret key whose trash should be listed. Defaults to the requesting key. Admins may leave it empty to list the whole trash.
+// sort: deleted_at (default), created_at, visits or last_visited_at
+type RetrieveTrashedLinksRequest struct {
+	Key string `json:"key"`
+	ListRequest
+	LinkFilterRequest
+}
+
+type RetrieveTrashedLinksResponse struct {
+	Message string                 `json:"message"`
+	Links   []RetrieveLinkResponse `json:"links"`
+	models.PageInfo
+}
+
+// RetrieveTrashedLinksHandler lists the links in the trash.
+// @Summary Retrieve trashed links
+// @Description Lists a page of the links in the trash, most recently trashed first by default. Non-admin keys can only list the trashed links they created or that live in a namespace they own.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RetrieveTrashedLinksRequest true "Trash retrieval request"
+// @Success 200 {object} RetrieveTrashedLinksResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/trash [post]
+func RetrieveTrashedLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		config := ErrorResponseConfig{
+			Status:    http.StatusMethodNotAllowed,
+			Message:   "Method not allowed",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if CheckUnauthorized(w, r) {
+		return
+	}
+
+	var request RetrieveTrashedLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	db := database.GetDB()
+	if db == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   lib.ERRORS.Database,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	filter, err := request.LinkFilterRequest.filter()
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	// non-admins only see the trashed links they could restore or purge: their own and those in namespaces they own
+	if !ctxValues.IsAdmin {
+		if request.Key != "" && request.Key != ctxValues.SecretKey {
+			config := ErrorResponseConfig{
+				Status:    http.StatusUnauthorized,
+				Message:   "Unauthorized to retrieve trashed links by key",
+				LogType:   models.LogTypeWarning,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		keyID := ctxValues.KeyID
+		filter.ManageableBy = &keyID
+	} else if request.Key != "" {
+		secretKey := models.SearchKeyByKey(db, request.Key)
+		if secretKey == nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusNotFound,
+				Message:   lib.ERRORS.KeyNotFound,
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+		filter.CreatedBy = &secretKey.ID
+	}
+
+	links, page, err := models.RetrieveTrashedLinks(db, filter, request.ListRequest.params())
+	if err != nil {
+		status, message := listErrorStatus(err)
+		config := ErrorResponseConfig{
+			Status:    status,
+			Message:   message,
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  err.Error(),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	response := RetrieveTrashedLinksResponse{
+		Message:  "Trashed links retrieved successfully",
+		Links:    ToRetrieveLinkResponses(links),
+		PageInfo: page,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Server Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceLinks,
+		"Retrieved trashed links. Requested by: '"+ctxValues.SecretKey+"'", r.RemoteAddr)
+}
+
+type TrashedLinkRequest struct {
+	Shortened string `json:"shortened"`
+}
+
+type TrashedLinkResponse struct {
+	Message string `json:"message"`
+}
+
+// RestoreLinkHandler restores a link from the trash.
+// @Summary Restore a trashed link
+// @Description Moves a link out of the trash so that it redirects again.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body TrashedLinkRequest true "Link restore request"
+// @Success 200 {object} TrashedLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/restore [post]
+func RestoreLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request TrashedLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, true)
+	if link == nil {
+		return
+	}
+
+	if err := models.RestoreLink(database.GetDB(), link); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to restore link",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TrashedLinkResponse{Message: "Link restored successfully"})
+
+	recordAuditEvent(r, ctxValues, models.AuditActionLinkRestore, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, nil, models.LinkAuditSnapshot(*link))
+}
+
+// PurgeLinkHandler permanently deletes a trashed link.
+// @Summary Purge a trashed link
+// @Description Permanently deletes a link from the trash along with its visit history. This frees its slug.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body TrashedLinkRequest true "Link purge request"
+// @Success 200 {object} TrashedLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/purge [post]
+func PurgeLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request TrashedLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, true)
+	if link == nil {
+		return
+	}
+
+	linkBefore := models.LinkAuditSnapshot(*link)
+
+	if err := models.PurgeLink(database.GetDB(), link); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to purge link",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TrashedLinkResponse{Message: "Link purged successfully"})
+
+	recordAuditEvent(r, ctxValues, models.AuditActionLinkPurge, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, linkBefore, nil)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// bcrypt ignores everything past 72 bytes, so longer passwords are rejected instead of silently truncated
+const maxLinkPasswordLength = 72
+
+// unlockLimiter rate-limits wrong password attempts per client, see GetClientKey
+var unlockLimiter = newFailedAttemptLimiter(lib.UNLOCK_MAX_FAILED_ATTEMPTS, lib.UNLOCK_FAILED_ATTEMPTS_WINDOW)
+
+// hashLinkPassword hashes a link password, an empty password removes protection and returns nil
+func hashLinkPassword(password string) (*string, error) {
+	if password == "" {
+		return nil, nil
+	}
+	if len(password) > maxLinkPasswordLength {
+		return nil, errors.New("password must be at most 72 bytes long")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	hashString := string(hash)
+	return &hashString, nil
+}
+
+// unlockHandler verifies the password posted by the unlock form, then redirects to the link's destination
+func unlockHandler(env *utils.Env) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := database.GetDB()
+
+		path, err := parseRequestPath(db, r, strings.TrimPrefix(r.URL.EscapedPath(), "/"))
+		fixedPath, rest := path.Display(), path.Rest
+		var linkObj *models.Link
+		if err == nil {
+			linkObj, _, err = resolveRedirectLink(db, path.Scope(), path.Slug)
+		}
+
+		if err == nil && !acceptsPath(*linkObj, rest) {
+			err = gorm.ErrRecordNotFound
+		}
+		if err != nil {
+			writeRedirectLookupError(w, r, path.Domain, fixedPath, err)
+			return
+		}
+
+		if !isLinkAvailable(*linkObj) {
+			serveFallback(w, r, db, env, *linkObj, fixedPath)
+			return
+		}
+
+		if !hasStarted(*linkObj) {
+			serveNotStarted(w, r, *linkObj, fixedPath)
+			return
+		}
+
+		if linkObj.PasswordHash == nil {
+			renderPage(w, r, PageError, http.StatusMethodNotAllowed, PageData{Slug: fixedPath, Error: "This link doesn't accept a password."})
+			return
+		}
+
+		clientKey := GetClientKey(r)
+		if retryAfter := unlockLimiter.RetryAfter(clientKey); retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			renderPage(w, r, PageRateLimited, http.StatusTooManyRequests, PageData{Slug: fixedPath, Error: "Too many wrong passwords. Please try again later."})
+			return
+		}
+
+		// the form only carries the password, so keep the body small
+		r.Body = http.MaxBytesReader(w, r.Body, 4096)
+		password := r.PostFormValue("password")
+
+		if bcrypt.CompareHashAndPassword([]byte(*linkObj.PasswordHash), []byte(password)) != nil {
+			unlockLimiter.RecordFailure(clientKey)
+			models.CreateLog(models.LogTypeWarning, models.LogSourceLinks,
+				"Wrong password for link '"+linkObj.Shortened+"'", clientKey)
+			renderUnlockPage(w, r, fixedPath, http.StatusUnauthorized, "Incorrect password.")
+			return
+		}
+
+		unlockLimiter.Reset(clientKey)
+		setUnlockCookie(w, r, *linkObj, env)
+
+		// 303 makes the browser follow up with a GET instead of re-posting the password to the destination
+		redirectToLink(w, r, db, env, *linkObj, fixedPath, rest, http.StatusSeeOther)
+	}
+}
+
+// unlockCookieName returns the name of the cookie that unlocks a link
+func unlockCookieName(link models.Link) string {
+	return "unlock_" + link.ID.String()
+}
+
+// unlockCookieSignature signs the link and expiry of an unlock cookie.
+// The password hash is part of the signature, so changing or removing the password invalidates existing cookies.
+func unlockCookieSignature(link models.Link, expires int64, env *utils.Env) string {
+	mac := hmac.New(sha256.New, []byte(env.UNLOCK_COOKIE_SECRET))
+	mac.Write([]byte(link.ID.String() + "|" + strconv.FormatInt(expires, 10) + "|"))
+	if link.PasswordHash != nil {
+		mac.Write([]byte(*link.PasswordHash))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// setUnlockCookie lets the browser skip the password form of the link until the cookie expires
+func setUnlockCookie(w http.ResponseWriter, r *http.Request, link models.Link, env *utils.Env) {
+	expiresAt := time.Now().Add(lib.UNLOCK_COOKIE_TTL)
+	expires := expiresAt.Unix()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookieName(link),
+		Value:    strconv.FormatInt(expires, 10) + "." + unlockCookieSignature(link, expires, env),
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   int(lib.UNLOCK_COOKIE_TTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// hasValidUnlockCookie checks if the request carries an unexpired, correctly signed unlock cookie for the link
+func hasValidUnlockCookie(r *http.Request, link models.Link, env *utils.Env) bool {
+	cookie, err := r.Cookie(unlockCookieName(link))
+	if err != nil {
+		return false
+	}
+
+	rawExpires, signature, found := strings.Cut(cookie.Value, ".")
+	if !found {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(rawExpires, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	expected := unlockCookieSignature(link, expires, env)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUnlockCookie(t *testing.T) {
+	hash := "$2a$10$hash"
+	link := models.Link{ID: uuid.New(), PasswordHash: &hash}
+	env := &utils.Env{UNLOCK_COOKIE_SECRET: "secret"}
+
+	recorder := httptest.NewRecorder()
+	setUnlockCookie(recorder, httptest.NewRequest(http.MethodPost, "/docs", nil), link, env)
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("setUnlockCookie set %d cookies, expected 1", len(cookies))
+	}
+
+	changedHash := "$2a$10$other"
+	expired := time.Now().Add(-time.Minute).Unix()
+	tests := []struct {
+		name     string
+		cookie   *http.Cookie
+		link     models.Link
+		env      *utils.Env
+		expected bool
+	}{
+		{"valid", cookies[0], link, env, true},
+		{"other secret", cookies[0], link, &utils.Env{UNLOCK_COOKIE_SECRET: "other"}, false},
+		{"changed password", cookies[0], models.Link{ID: link.ID, PasswordHash: &changedHash}, env, false},
+		{"other link", &http.Cookie{Name: unlockCookieName(models.Link{ID: uuid.New()}), Value: cookies[0].Value}, link, env, false},
+		{"tampered expiry", &http.Cookie{Name: cookies[0].Name, Value: "9999999999." + unlockCookieSignature(link, expired, env)}, link, env, false},
+		{"expired", &http.Cookie{Name: cookies[0].Name, Value: strconv.FormatInt(expired, 10) + "." + unlockCookieSignature(link, expired, env)}, link, env, false},
+		{"malformed", &http.Cookie{Name: cookies[0].Name, Value: "garbage"}, link, env, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			r.AddCookie(test.cookie)
+			if valid := hasValidUnlockCookie(r, test.link, test.env); valid != test.expected {
+				t.Errorf("hasValidUnlockCookie = %t, expected %t", valid, test.expected)
+			}
+		})
+	}
+}
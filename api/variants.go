@@ -0,0 +1,677 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pickVariant returns the variant that a roll in [0, total weight) falls on, or nil if no variant has any weight
+func pickVariant(variants []models.LinkVariant, roll uint64) *models.LinkVariant {
+	var totalWeight uint64
+	for _, variant := range variants {
+		totalWeight += uint64(variant.Weight)
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	roll %= totalWeight
+	for i := range variants {
+		weight := uint64(variants[i].Weight)
+		if roll < weight {
+			return &variants[i]
+		}
+		roll -= weight
+	}
+	return nil
+}
+
+// variantRoll returns the number used to pick a variant. Sticky links derive it from a hash of the visitor cookie
+// and the link, so a returning visitor lands on the same variant, other links roll randomly on every visit.
+func variantRoll(w http.ResponseWriter, r *http.Request, link models.Link) uint64 {
+	if !link.StickyVariants {
+		roll, err := rand.Int(rand.Reader, new(big.Int).SetUint64(1<<63))
+		if err != nil {
+			return 0
+		}
+		return roll.Uint64()
+	}
+
+	visitorID := ensureVisitorCookie(w, r)
+	hash := sha256.Sum256([]byte(visitorID + "|" + link.ID.String()))
+	return binary.BigEndian.Uint64(hash[:8])
+}
+
+// ensureVisitorCookie returns the visitor's random ID, setting a new visitor cookie if the request has none.
+// The ID identifies a browser only, it isn't derived from anything about the visitor.
+func ensureVisitorCookie(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(lib.VISITOR_COOKIE_NAME); err == nil && len(cookie.Value) == 32 {
+		if _, err := hex.DecodeString(cookie.Value); err == nil {
+			return cookie.Value
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return ""
+	}
+	visitorID := hex.EncodeToString(idBytes)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     lib.VISITOR_COOKIE_NAME,
+		Value:    visitorID,
+		Path:     "/",
+		Expires:  time.Now().Add(lib.VISITOR_COOKIE_TTL),
+		MaxAge:   int(lib.VISITOR_COOKIE_TTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	return visitorID
+}
+
+type LinkVariantResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	RedirectTo string    `json:"redirect_to"`
+	Weight     int       `json:"weight"`
+}
+
+// convert []models.LinkVariant to []LinkVariantResponse
+func ToLinkVariantResponses(variants []models.LinkVariant) []LinkVariantResponse {
+	responses := make([]LinkVariantResponse, 0, len(variants))
+	for _, variant := range variants {
+		responses = append(responses, LinkVariantResponse{
+			ID:         variant.ID,
+			Name:       variant.Name,
+			RedirectTo: variant.RedirectTo,
+			Weight:     variant.Weight,
+		})
+	}
+	return responses
+}
+
+// validateVariantName checks that a variant name is alphanumeric and fits the column
+func validateVariantName(name string) error {
+	if name == "" || len(name) > 50 || !isAlphanumeric(name) {
+		return errors.New("name must be alphanumeric and at most 50 characters long")
+	}
+	return nil
+}
+
+// validateVariantWeight checks that a variant weight is within bounds
+func validateVariantWeight(weight int) error {
+	if weight < 0 || weight > lib.MAX_VARIANT_WEIGHT {
+		return fmt.Errorf("weight must be between 0 and %d", lib.MAX_VARIANT_WEIGHT)
+	}
+	return nil
+}
+
+// variantAuditSnapshot returns the audited fields of a variant
+func variantAuditSnapshot(variant models.LinkVariant) map[string]interface{} {
+	return map[string]interface{}{
+		"variant":     variant.Name,
+		"redirect_to": variant.RedirectTo,
+		"weight":      variant.Weight,
+	}
+}
+
+// name: Identifies the variant within the link, e.g. "a" or "control"
+// weight: The variant's share of visits relative to the other variants, e.g. 50 and 50, or 90 and 10
+type AddLinkVariantRequest struct {
+	Shortened  string `json:"shortened"`
+	Name       string `json:"name"`
+	RedirectTo string `json:"redirect_to"`
+	Weight     int    `json:"weight"`
+}
+
+// AddLinkVariantHandler adds an A/B variant to a link.
+// @Summary Add a variant to a link
+// @Description Adds a weighted destination to a link. Once a link has variants, visits are split between them by weight instead of going to redirect_to.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body AddLinkVariantRequest true "Variant request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/add-variant [post]
+func AddLinkVariantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request AddLinkVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	redirectTo, err := validateAndNormalizeURL(request.RedirectTo)
+	if err == nil {
+		err = validateVariantName(request.Name)
+	}
+	if err == nil {
+		err = validateVariantWeight(request.Weight)
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	db := database.GetDB()
+
+	if _, err := models.FindLinkVariant(db, link.ID, request.Name); err == nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusConflict,
+			Message:   "A variant with this name already exists",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	variant := models.LinkVariant{
+		LinkID:     link.ID,
+		Name:       request.Name,
+		RedirectTo: redirectTo,
+		Weight:     request.Weight,
+	}
+	if err := db.Create(&variant).Error; err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to add variant",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	link.Variants = append(link.Variants, variant)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionVariantAdd, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, nil, variantAuditSnapshot(variant))
+}
+
+// name: The variant to update
+// new_name, redirect_to, weight: The new values, fields that are left out stay unchanged
+type UpdateLinkVariantRequest struct {
+	Shortened  string  `json:"shortened"`
+	Name       string  `json:"name"`
+	NewName    *string `json:"new_name,omitempty"`
+	RedirectTo *string `json:"redirect_to,omitempty"`
+	Weight     *int    `json:"weight,omitempty"`
+}
+
+// UpdateLinkVariantHandler updates an A/B variant of a link.
+// @Summary Update a variant of a link
+// @Description Changes the name, destination or weight of a link variant.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body UpdateLinkVariantRequest true "Variant update request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/update-variant [post]
+func UpdateLinkVariantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request UpdateLinkVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	db := database.GetDB()
+
+	variant, err := models.FindLinkVariant(db, link.ID, request.Name)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusNotFound,
+			Message:   "Variant not found",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Shortened: %s, Variant: %s", request.Shortened, request.Name),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+	variantBefore := variantAuditSnapshot(*variant)
+
+	var validationErr error
+	if request.NewName != nil && *request.NewName != variant.Name {
+		if validationErr = validateVariantName(*request.NewName); validationErr == nil {
+			if _, err := models.FindLinkVariant(db, link.ID, *request.NewName); err == nil {
+				config := ErrorResponseConfig{
+					Status:    http.StatusConflict,
+					Message:   "A variant with this name already exists",
+					LogType:   models.LogTypeError,
+					LogSource: models.LogSourceLinks,
+					Request:   r,
+					CtxValues: &ctxValues,
+				}
+				writeErrorResponse(w, config)
+				return
+			}
+			variant.Name = *request.NewName
+		}
+	}
+	if validationErr == nil && request.RedirectTo != nil {
+		var redirectTo string
+		if redirectTo, validationErr = validateAndNormalizeURL(*request.RedirectTo); validationErr == nil {
+			variant.RedirectTo = redirectTo
+		}
+	}
+	if validationErr == nil && request.Weight != nil {
+		if validationErr = validateVariantWeight(*request.Weight); validationErr == nil {
+			variant.Weight = *request.Weight
+		}
+	}
+	if validationErr != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   validationErr.Error(),
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	if err := db.Save(variant).Error; err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to update variant",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	for i := range link.Variants {
+		if link.Variants[i].ID == variant.ID {
+			link.Variants[i] = *variant
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionVariantUpdate, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, variantBefore, variantAuditSnapshot(*variant))
+}
+
+// name: The variant to remove
+type RemoveLinkVariantRequest struct {
+	Shortened string `json:"shortened"`
+	Name      string `json:"name"`
+}
+
+// RemoveLinkVariantHandler removes an A/B variant from a link.
+// @Summary Remove a variant from a link
+// @Description Removes a variant from a link. Its recorded visits are kept. Once the last variant is removed, visits go to redirect_to again.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RemoveLinkVariantRequest true "Variant removal request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/remove-variant [post]
+func RemoveLinkVariantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RemoveLinkVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	db := database.GetDB()
+
+	variant, err := models.FindLinkVariant(db, link.ID, request.Name)
+	if err == nil {
+		err = db.Delete(variant).Error
+	}
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to remove variant",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			config.Status = http.StatusNotFound
+			config.Message = "Variant not found"
+			config.Addendum = fmt.Sprintf("Shortened: %s, Variant: %s", request.Shortened, request.Name)
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	remaining := make([]models.LinkVariant, 0, len(link.Variants))
+	for _, linkVariant := range link.Variants {
+		if linkVariant.ID != variant.ID {
+			remaining = append(remaining, linkVariant)
+		}
+	}
+	link.Variants = remaining
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionVariantRemove, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, variantAuditSnapshot(*variant), nil)
+}
+
+// weights: The new weight of each variant by name, variants that are left out keep their weight
+type ReweightLinkVariantsRequest struct {
+	Shortened string         `json:"shortened"`
+	Weights   map[string]int `json:"weights"`
+}
+
+// ReweightLinkVariantsHandler changes the weights of several variants of a link at once.
+// @Summary Reweight the variants of a link
+// @Description Changes the weights of several variants in one step, e.g. to move an experiment from 50/50 to 90/10.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ReweightLinkVariantsRequest true "Variant reweight request"
+// @Success 200 {object} RetrieveLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/reweight-variants [post]
+func ReweightLinkVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request ReweightLinkVariantsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	for _, weight := range request.Weights {
+		if err := validateVariantWeight(weight); err != nil {
+			config := ErrorResponseConfig{
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				LogType:   models.LogTypeError,
+				LogSource: models.LogSourceLinks,
+				Request:   r,
+				CtxValues: &ctxValues,
+			}
+			writeErrorResponse(w, config)
+			return
+		}
+	}
+
+	weightsBefore := make(map[string]interface{}, len(link.Variants))
+	for _, variant := range link.Variants {
+		weightsBefore[variant.Name] = variant.Weight
+	}
+
+	db := database.GetDB()
+
+	if err := models.ReweightLinkVariants(db, link.ID, request.Weights); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Failed to reweight variants",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			config.Status = http.StatusNotFound
+			config.Message = "Variant not found"
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	weightsAfter := make(map[string]interface{}, len(link.Variants))
+	for i := range link.Variants {
+		if weight, ok := request.Weights[link.Variants[i].Name]; ok {
+			link.Variants[i].Weight = weight
+		}
+		weightsAfter[link.Variants[i].Name] = link.Variants[i].Weight
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ToRetrieveLinkResponse(*link))
+
+	recordAuditEvent(r, ctxValues, models.AuditActionVariantReweight, models.AuditTargetLink,
+		link.ID.String(), link.Shortened, weightsBefore, weightsAfter)
+}
+
+type RetrieveVariantStatsRequest struct {
+	Shortened string `json:"shortened"`
+}
+
+// share: The variant's fraction of all visits that went to a variant
+type VariantStatsResponse struct {
+	Name       string  `json:"name"`
+	RedirectTo string  `json:"redirect_to"`
+	Weight     int     `json:"weight"`
+	Visits     int64   `json:"visits"`
+	Share      float64 `json:"share"`
+}
+
+type RetrieveVariantStatsResponse struct {
+	Message  string                 `json:"message"`
+	Variants []VariantStatsResponse `json:"variants"`
+}
+
+// RetrieveVariantStatsHandler reports the visits of each A/B variant of a link.
+// @Summary Retrieve variant analytics
+// @Description Reports how many visits each variant of a link received and its share of all variant visits.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body RetrieveVariantStatsRequest true "Variant analytics request"
+// @Success 200 {object} RetrieveVariantStatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/links/variant-stats [post]
+func RetrieveVariantStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request RetrieveVariantStatsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusBadRequest,
+			Message:   "Invalid request body",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	ctxValues, _ := GetContextValues(r)
+
+	link := retrieveManageableLink(w, r, ctxValues, request.Shortened, false)
+	if link == nil {
+		return
+	}
+
+	counts, err := models.CountVariantVisits(database.GetDB(), link.ID)
+	if err != nil {
+		config := ErrorResponseConfig{
+			Status:    http.StatusInternalServerError,
+			Message:   "Database Error",
+			LogType:   models.LogTypeError,
+			LogSource: models.LogSourceLinks,
+			Request:   r,
+			CtxValues: &ctxValues,
+			Addendum:  fmt.Sprintf("Error: %v", err),
+		}
+		writeErrorResponse(w, config)
+		return
+	}
+
+	visitsByVariant := make(map[uuid.UUID]int64, len(counts))
+	var totalVisits int64
+	for _, count := range counts {
+		visitsByVariant[count.VariantID] = count.Visits
+		totalVisits += count.Visits
+	}
+
+	stats := make([]VariantStatsResponse, 0, len(link.Variants))
+	for _, variant := range link.Variants {
+		visits := visitsByVariant[variant.ID]
+		share := 0.0
+		if totalVisits > 0 {
+			share = float64(visits) / float64(totalVisits)
+		}
+		stats = append(stats, VariantStatsResponse{
+			Name:       variant.Name,
+			RedirectTo: variant.RedirectTo,
+			Weight:     variant.Weight,
+			Visits:     visits,
+			Share:      share,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetrieveVariantStatsResponse{
+		Message:  "Variant analytics retrieved successfully",
+		Variants: stats,
+	})
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"go-link-shortener/models"
+	"testing"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []models.LinkVariant{
+		{Name: "a", Weight: 90},
+		{Name: "off", Weight: 0},
+		{Name: "b", Weight: 10},
+	}
+
+	tests := map[uint64]string{0: "a", 89: "a", 90: "b", 99: "b", 100: "a", 195: "b"}
+	for roll, expected := range tests {
+		if variant := pickVariant(variants, roll); variant == nil || variant.Name != expected {
+			t.Errorf("pickVariant(%d) = %v, expected %q", roll, variant, expected)
+		}
+	}
+
+	if variant := pickVariant([]models.LinkVariant{{Name: "off", Weight: 0}}, 5); variant != nil {
+		t.Errorf("pickVariant with no weight = %q, expected nil", variant.Name)
+	}
+}
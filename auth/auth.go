@@ -46,6 +46,10 @@ type UpdateKeyS struct {
 	Key      *string
 	IsActive *bool
 	IsAdmin  *bool
+	// FallbackURL is validated by the caller, an empty string removes the key's fallback
+	FallbackURL *string
+	// DisplayName is shown publicly on link previews, an empty string hides the owner
+	DisplayName *string
 }
 
 // UpdateKey updates the properties of an existing secret key.
@@ -84,8 +88,27 @@ func UpdateKey(request UpdateKeyS) (string, *models.SecretKey, error) {
 		updateKeyObj.IsAdmin = *request.IsAdmin
 	}
 
+	if request.FallbackURL != nil {
+		if *request.FallbackURL == "" {
+			updateKeyObj.FallbackURL = nil
+		} else {
+			updateKeyObj.FallbackURL = request.FallbackURL
+		}
+	}
+
+	if request.DisplayName != nil {
+		if len(*request.DisplayName) > 100 {
+			return "", nil, errors.New(lib.ERRORS.DisplayNameTooLong)
+		}
+		if *request.DisplayName == "" {
+			updateKeyObj.DisplayName = nil
+		} else {
+			updateKeyObj.DisplayName = request.DisplayName
+		}
+	}
+
 	// if all of the fields except for the key are nil, return an error with message "no fields to update"
-	if request.Name == nil && request.IsActive == nil && request.IsAdmin == nil {
+	if request.Name == nil && request.IsActive == nil && request.IsAdmin == nil && request.FallbackURL == nil && request.DisplayName == nil {
 		return "", nil, errors.New(lib.ERRORS.NoNewFields)
 	}
 
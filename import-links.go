@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"go-link-shortener/api"
+	"go-link-shortener/database"
+	"go-link-shortener/importer"
+	"go-link-shortener/lib"
+	"go-link-shortener/models"
+	"go-link-shortener/utils"
+)
+
+// runImport imports the links of an export file, like /v1/links/import but without its size limit.
+// It returns the exit code: 1 if the import couldn't run or any link failed, conflicts alone don't fail it.
+//
+//	go-link-shortener import -format yourls -file yourls.csv [-key KEY] [-domain HOST] [-namespace NAME] [-on-conflict skip|rename] [-dry-run]
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "format of the export: csv, json, yourls, shlink, bitly or nginx")
+	file := flags.String("file", "", "path of the export, - to read it from stdin")
+	key := flags.String("key", "", "key the links are assigned to (default ROOT_USER_KEY)")
+	domain := flags.String("domain", "", "host of the domain the links are imported into (default PUBLIC_SITE_URL)")
+	namespace := flags.String("namespace", "", "namespace the links are imported into")
+	onConflict := flags.String("on-conflict", string(api.ImportConflictSkip), "what happens to links whose slug is taken or invalid: skip or rename")
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format == "" || *file == "" {
+		fmt.Fprintln(os.Stderr, "-format and -file are required")
+		flags.Usage()
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Println("❌ Failed to open the export:", err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	records, err := importer.Parse(importer.Format(*format), input)
+	if err != nil {
+		log.Println("❌ Failed to read the export:", err)
+		return 1
+	}
+
+	env := utils.LoadEnv()
+	database.SetDB(database.ConnectToDatabase(env))
+	db := database.GetDB()
+	if err := models.SetupDatabase(db); err != nil {
+		log.Println("❌ Failed to set up the database:", err)
+		return 1
+	}
+
+	if *key == "" {
+		*key = env.ROOT_USER_KEY
+	}
+	secretKey := models.SearchKeyByKey(db, *key)
+	if secretKey == nil {
+		log.Println("❌", lib.ERRORS.KeyNotFound)
+		return 1
+	}
+
+	opts := api.ImportOptions{
+		KeyID:      secretKey.ID,
+		Domain:     *domain,
+		Namespace:  *namespace,
+		OnConflict: api.ImportConflictPolicy(*onConflict),
+		DryRun:     *dryRun,
+	}
+	report, created, err := api.ImportLinks(db, records, opts)
+	if err != nil {
+		log.Println("❌ Import failed:", err)
+		return 1
+	}
+
+	for _, result := range report.Results {
+		line := fmt.Sprintf("%6d  %-8s  %s", result.Position, result.Status, result.Shortened)
+		if result.ImportedAs != "" && result.ImportedAs != result.Shortened {
+			line += " -> " + result.ImportedAs
+		}
+		if result.Error != "" {
+			line += ": " + result.Error
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%s: %d created, %d renamed, %d conflicts, %d failed\n",
+		report.Message, report.Created, report.Renamed, report.Conflicts, report.Failed)
+
+	models.CreateLog(models.LogTypeInfo, models.LogSourceLinks,
+		fmt.Sprintf("Imported %d of %d %s links from the command line (dry run: %t). Assigned to: '%s'", report.Created+report.Renamed, len(records), *format, *dryRun, secretKey.Name), "")
+	for _, link := range created {
+		models.CreateAuditEvent(models.AuditEvent{
+			ActorKeyID: secretKey.ID,
+			Action:     models.AuditActionLinkCreate,
+			TargetType: models.AuditTargetLink,
+			TargetID:   link.ID.String(),
+			TargetName: link.Shortened,
+		}, nil, models.LinkAuditSnapshot(link))
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
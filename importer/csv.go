@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvSchema lists the header names a CSV export uses for each field of a Record, the first one present is used.
+// Header names are compared in lowercase, with spaces and dashes replaced by underscores.
+type csvSchema struct {
+	slug       []string
+	redirectTo []string
+	createdAt  []string
+	visits     []string
+	title      []string
+	notes      []string
+	tags       []string
+	folder     []string
+}
+
+// ownCSVSchema uses the field names of the API, tags are separated by commas, semicolons or pipes
+var ownCSVSchema = csvSchema{
+	slug:       []string{"shortened", "slug"},
+	redirectTo: []string{"redirect_to"},
+	createdAt:  []string{"created_at"},
+	visits:     []string{"visits"},
+	title:      []string{"title"},
+	notes:      []string{"notes"},
+	tags:       []string{"tags"},
+	folder:     []string{"folder"},
+}
+
+// yourlsCSVSchema reads a dump of the yourls_url table
+var yourlsCSVSchema = csvSchema{
+	slug:       []string{"keyword"},
+	redirectTo: []string{"url"},
+	createdAt:  []string{"timestamp"},
+	visits:     []string{"clicks"},
+	title:      []string{"title"},
+}
+
+// shlinkCSVSchema reads the CSV export of the Shlink web client
+var shlinkCSVSchema = csvSchema{
+	slug:       []string{"shortcode", "short_code", "shorturl", "short_url"},
+	redirectTo: []string{"longurl", "long_url"},
+	createdAt:  []string{"datecreated", "date_created", "createdat", "created_at"},
+	visits:     []string{"visits", "visitscount", "visits_count"},
+	title:      []string{"title"},
+	tags:       []string{"tags"},
+}
+
+// bitlyCSVSchema reads the link export of the Bitly dashboard
+var bitlyCSVSchema = csvSchema{
+	slug:       []string{"bitlink", "link", "id"},
+	redirectTo: []string{"long_url", "original_url", "destination"},
+	createdAt:  []string{"created", "created_at", "date_created"},
+	visits:     []string{"clicks", "total_clicks", "engagements"},
+	title:      []string{"title"},
+	tags:       []string{"tags"},
+}
+
+// normalizeHeader makes header names of different exports comparable
+func normalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// parseCSV reads a CSV export with a header row
+func parseCSV(data []byte, schema csvSchema) ([]Record, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the CSV file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, ok := columns[normalizeHeader(name)]; !ok {
+			columns[normalizeHeader(name)] = i
+		}
+	}
+	// column returns the index of the first header name present, -1 if none is
+	column := func(names []string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	slugColumn, redirectColumn := column(schema.slug), column(schema.redirectTo)
+	createdAtColumn, visitsColumn := column(schema.createdAt), column(schema.visits)
+	titleColumn, notesColumn := column(schema.title), column(schema.notes)
+	tagsColumn, folderColumn := column(schema.tags), column(schema.folder)
+	if redirectColumn < 0 {
+		return nil, fmt.Errorf("the CSV header has no %s column", schema.redirectTo[0])
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			// a malformed row stops the reader, so the rest of the file can't be trusted either
+			return nil, fmt.Errorf("invalid CSV on line %d: %v", line, err)
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		record := Record{
+			Position:   line,
+			Slug:       slugFromShortURL(field(slugColumn)),
+			RedirectTo: field(redirectColumn),
+			Title:      field(titleColumn),
+			Notes:      field(notesColumn),
+			Tags:       splitTags(field(tagsColumn)),
+			Folder:     field(folderColumn),
+		}
+		record.CreatedAt, record.Err = parseTime(field(createdAtColumn))
+		if record.Err == nil {
+			record.Visits, record.Err = parseVisits(field(visitsColumn))
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the layout of an exported list of links
+type Format string
+
+const (
+	// FormatCSV is this shortener's own CSV schema, see ownCSVSchema
+	FormatCSV Format = "csv"
+	// FormatJSON is this shortener's own JSON schema, e.g. the response of /v1/links/retrieve-all
+	FormatJSON Format = "json"
+	// FormatYOURLS is a CSV dump of the yourls_url table or the JSON of the YOURLS stats API
+	FormatYOURLS Format = "yourls"
+	// FormatShlink is the JSON of the Shlink short URL API or a CSV export of the Shlink web client
+	FormatShlink Format = "shlink"
+	// FormatBitly is the JSON of the Bitly bitlinks API or a CSV export of the Bitly dashboard
+	FormatBitly Format = "bitly"
+	// FormatNginx is an nginx map file mapping paths to destinations
+	FormatNginx Format = "nginx"
+)
+
+// Formats lists every supported format
+var Formats = []Format{FormatCSV, FormatJSON, FormatYOURLS, FormatShlink, FormatBitly, FormatNginx}
+
+// ErrUnknownFormat is returned for formats that aren't in Formats
+var ErrUnknownFormat = errors.New("format must be one of csv, json, yourls, shlink, bitly or nginx")
+
+// Record is a link read from an export. Fields the export doesn't have are left empty.
+type Record struct {
+	// Position is the line of CSV and nginx records and the 1-based index of JSON records
+	Position   int
+	Slug       string
+	RedirectTo string
+	CreatedAt  *time.Time
+	Visits     int
+	Title      string
+	Notes      string
+	Tags       []string
+	Folder     string
+	// Err tells why the record couldn't be read, the other records are still imported
+	Err error
+}
+
+// Parse reads the records of an export. Records that can't be read carry their error,
+// an error is only returned if the export as a whole can't be read.
+func Parse(format Format, r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatCSV:
+		return parseCSV(data, ownCSVSchema)
+	case FormatJSON:
+		return parseOwnJSON(data)
+	case FormatYOURLS:
+		if isJSON(data) {
+			return parseYOURLSJSON(data)
+		}
+		return parseCSV(data, yourlsCSVSchema)
+	case FormatShlink:
+		if isJSON(data) {
+			return parseShlinkJSON(data)
+		}
+		return parseCSV(data, shlinkCSVSchema)
+	case FormatBitly:
+		if isJSON(data) {
+			return parseBitlyJSON(data)
+		}
+		return parseCSV(data, bitlyCSVSchema)
+	case FormatNginx:
+		return parseNginxMap(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// isJSON tells JSON exports apart from CSV exports of the same shortener
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// timeLayouts are the timestamp layouts used by the supported exports, times without a zone are read as UTC
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700", // Bitly
+	"2006-01-02 15:04:05",      // YOURLS
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime reads an optional timestamp
+func parseTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date '%s'", value)
+}
+
+// parseVisits reads an optional visit count, which some exports write with thousands separators
+func parseVisits(value string) (int, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	if value == "" {
+		return 0, nil
+	}
+	visits, err := strconv.Atoi(value)
+	if err != nil || visits < 0 {
+		return 0, fmt.Errorf("invalid visit count '%s'", value)
+	}
+	return visits, nil
+}
+
+// slugFromShortURL returns the slug of a short URL such as "https://bit.ly/abc" or "bit.ly/abc",
+// values that aren't URLs are returned as they are
+func slugFromShortURL(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "://") {
+		if u, err := url.Parse(value); err == nil {
+			return strings.Trim(u.Path, "/")
+		}
+	}
+	// bare "host/slug" references, as in Bitly's link ids
+	if host, slug, found := strings.Cut(value, "/"); found && strings.Contains(host, ".") {
+		return strings.Trim(slug, "/")
+	}
+	return value
+}
+
+// splitTags splits a list of tags separated by commas, semicolons or pipes
+func splitTags(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == '|' })
+	tags := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if tag := strings.TrimSpace(field); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,126 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	created := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		format   Format
+		data     string
+		expected []Record
+	}{
+		{
+			name:   "own CSV",
+			format: FormatCSV,
+			data:   "Shortened,Redirect To,Created At,Visits,Tags\ndocs,https://example.com/docs,2023-05-01T10:30:00Z,\"1,204\",eng|docs\n",
+			expected: []Record{
+				{Position: 2, Slug: "docs", RedirectTo: "https://example.com/docs", CreatedAt: &created, Visits: 1204, Tags: []string{"eng", "docs"}},
+			},
+		},
+		{
+			name:   "own JSON",
+			format: FormatJSON,
+			data:   `{"links": [{"shortened": "docs", "redirect_to": "https://example.com/docs", "created_at": "2023-05-01T10:30:00Z", "visits": 3, "title": null, "tags": ["eng"]}]}`,
+			expected: []Record{
+				{Position: 1, Slug: "docs", RedirectTo: "https://example.com/docs", CreatedAt: &created, Visits: 3, Tags: []string{"eng"}},
+			},
+		},
+		{
+			name:   "YOURLS CSV",
+			format: FormatYOURLS,
+			data:   "keyword,url,title,timestamp,ip,clicks\ndocs,https://example.com/docs,Docs,2023-05-01 10:30:00,127.0.0.1,7\n",
+			expected: []Record{
+				{Position: 2, Slug: "docs", RedirectTo: "https://example.com/docs", Title: "Docs", CreatedAt: &created, Visits: 7, Tags: []string{}},
+			},
+		},
+		{
+			name:   "YOURLS JSON",
+			format: FormatYOURLS,
+			data:   `{"links": {"link_10": {"shorturl": "https://sho.rt/b", "url": "https://example.com/b", "clicks": 2}, "link_2": {"shorturl": "https://sho.rt/a", "url": "https://example.com/a", "timestamp": "2023-05-01 10:30:00", "clicks": "5"}}}`,
+			expected: []Record{
+				{Position: 1, Slug: "a", RedirectTo: "https://example.com/a", CreatedAt: &created, Visits: 5},
+				{Position: 2, Slug: "b", RedirectTo: "https://example.com/b", Visits: 2},
+			},
+		},
+		{
+			name:   "Shlink JSON",
+			format: FormatShlink,
+			data:   `{"shortUrls": {"data": [{"shortCode": "docs", "longUrl": "https://example.com/docs", "dateCreated": "2023-05-01T10:30:00+00:00", "visitsSummary": {"total": 9}, "tags": ["eng"]}]}}`,
+			expected: []Record{
+				{Position: 1, Slug: "docs", RedirectTo: "https://example.com/docs", CreatedAt: &created, Visits: 9, Tags: []string{"eng"}},
+			},
+		},
+		{
+			name:   "Bitly JSON",
+			format: FormatBitly,
+			data:   `{"links": [{"id": "bit.ly/3abc", "link": "https://bit.ly/3abc", "long_url": "https://example.com/docs", "created_at": "2023-05-01T10:30:00+0000"}]}`,
+			expected: []Record{
+				{Position: 1, Slug: "3abc", RedirectTo: "https://example.com/docs", CreatedAt: &created},
+			},
+		},
+		{
+			name:   "nginx map",
+			format: FormatNginx,
+			data:   "map $uri $redirect {\n    default \"\";\n    /docs https://example.com/docs; # docs\n    \"/blog/\" 'https://example.com/blog';\n}\n",
+			expected: []Record{
+				{Position: 3, Slug: "docs", RedirectTo: "https://example.com/docs"},
+				{Position: 4, Slug: "blog", RedirectTo: "https://example.com/blog"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, err := Parse(test.format, strings.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("Parse error = %v", err)
+			}
+			if !reflect.DeepEqual(records, test.expected) {
+				t.Errorf("Parse = %+v, expected %+v", records, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseRecordErrors(t *testing.T) {
+	records, err := Parse(FormatNginx, strings.NewReader("~^/old/(.*)$ https://example.com/$1;\n/docs https://example.com/docs\n"))
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	if len(records) != 2 || records[0].Err == nil || records[1].Err == nil {
+		t.Errorf("Parse = %+v, expected two records with errors", records)
+	}
+
+	records, err = Parse(FormatCSV, strings.NewReader("shortened,redirect_to,visits\ndocs,https://example.com/docs,many\n"))
+	if err != nil || len(records) != 1 || records[0].Err == nil {
+		t.Errorf("Parse = %+v, %v, expected a record with an invalid visit count", records, err)
+	}
+
+	if _, err := Parse(FormatCSV, strings.NewReader("shortened,destination\ndocs,https://example.com/docs\n")); err == nil {
+		t.Error("Parse accepted a CSV file without a redirect_to column")
+	}
+	if _, err := Parse("wordpress", strings.NewReader("")); err != ErrUnknownFormat {
+		t.Errorf("Parse error = %v, expected ErrUnknownFormat", err)
+	}
+}
+
+func TestSlugFromShortURL(t *testing.T) {
+	tests := map[string]string{
+		"https://bit.ly/3abc": "3abc",
+		"bit.ly/3abc":         "3abc",
+		" docs ":              "docs",
+		"eng/docs":            "eng/docs",
+	}
+	for value, expected := range tests {
+		if slug := slugFromShortURL(value); slug != expected {
+			t.Errorf("slugFromShortURL(%q) = %q, expected %q", value, slug, expected)
+		}
+	}
+}
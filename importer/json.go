@@ -0,0 +1,225 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonItems returns the items of a JSON export, which is either an array or an object wrapping it.
+// items extracts the array from the wrapping object.
+func jsonItems(data []byte, wrapper interface{}, items func() []json.RawMessage) ([]json.RawMessage, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) > 0 && data[0] == '[' {
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return list, nil
+	}
+	if err := json.Unmarshal(data, wrapper); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return items(), nil
+}
+
+// decodeItems decodes every item into a Record, items that can't be decoded carry their error
+func decodeItems[T any](items []json.RawMessage, convert func(T) Record) []Record {
+	records := make([]Record, 0, len(items))
+	for i, item := range items {
+		var value T
+		var record Record
+		if err := json.Unmarshal(item, &value); err != nil {
+			record.Err = fmt.Errorf("invalid link: %v", err)
+		} else {
+			record = convert(value)
+		}
+		record.Position = i + 1
+		records = append(records, record)
+	}
+	return records
+}
+
+// flexibleCount is a visit count that exports write either as a number or as a string
+type flexibleCount string
+
+func (c *flexibleCount) UnmarshalJSON(data []byte) error {
+	*c = flexibleCount(strings.Trim(string(data), `"`))
+	if *c == "null" {
+		*c = ""
+	}
+	return nil
+}
+
+// withTimeAndVisits parses the creation time and visit count of a record, keeping the first error
+func withTimeAndVisits(record Record, createdAt string, visits flexibleCount) Record {
+	record.CreatedAt, record.Err = parseTime(createdAt)
+	if record.Err == nil {
+		record.Visits, record.Err = parseVisits(string(visits))
+	}
+	return record
+}
+
+// ownJSONLink is a link as the API returns it
+type ownJSONLink struct {
+	Shortened  string        `json:"shortened"`
+	RedirectTo string        `json:"redirect_to"`
+	CreatedAt  string        `json:"created_at"`
+	Visits     flexibleCount `json:"visits"`
+	Title      *string       `json:"title"`
+	Notes      *string       `json:"notes"`
+	Tags       []string      `json:"tags"`
+	Folder     *string       `json:"folder"`
+}
+
+func parseOwnJSON(data []byte) ([]Record, error) {
+	var wrapper struct {
+		Links []json.RawMessage `json:"links"`
+	}
+	items, err := jsonItems(data, &wrapper, func() []json.RawMessage { return wrapper.Links })
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeItems(items, func(link ownJSONLink) Record {
+		record := Record{
+			Slug:       strings.TrimSpace(link.Shortened),
+			RedirectTo: strings.TrimSpace(link.RedirectTo),
+			Title:      stringValue(link.Title),
+			Notes:      stringValue(link.Notes),
+			Tags:       link.Tags,
+			Folder:     stringValue(link.Folder),
+		}
+		return withTimeAndVisits(record, link.CreatedAt, link.Visits)
+	}), nil
+}
+
+// yourlsJSONLink is a link of the YOURLS stats API, e.g. /yourls-api.php?action=stats&filter=last&limit=100000&format=json
+type yourlsJSONLink struct {
+	ShortURL  string        `json:"shorturl"`
+	URL       string        `json:"url"`
+	Title     string        `json:"title"`
+	Timestamp string        `json:"timestamp"`
+	Clicks    flexibleCount `json:"clicks"`
+}
+
+func parseYOURLSJSON(data []byte) ([]Record, error) {
+	var response struct {
+		Links map[string]json.RawMessage `json:"links"`
+	}
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &response); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	// the links are keyed "link_1", "link_2", ... in the order of the stats
+	keys := make([]string, 0, len(response.Links))
+	for key := range response.Links {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(strings.TrimPrefix(keys[i], "link_"))
+		b, errB := strconv.Atoi(strings.TrimPrefix(keys[j], "link_"))
+		if errA != nil || errB != nil {
+			return keys[i] < keys[j]
+		}
+		return a < b
+	})
+	items := make([]json.RawMessage, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, response.Links[key])
+	}
+
+	return decodeItems(items, func(link yourlsJSONLink) Record {
+		record := Record{
+			Slug:       slugFromShortURL(link.ShortURL),
+			RedirectTo: strings.TrimSpace(link.URL),
+			Title:      strings.TrimSpace(link.Title),
+		}
+		return withTimeAndVisits(record, link.Timestamp, link.Clicks)
+	}), nil
+}
+
+// shlinkJSONLink is a short URL of the Shlink REST API (/rest/v3/short-urls)
+type shlinkJSONLink struct {
+	ShortCode     string        `json:"shortCode"`
+	LongURL       string        `json:"longUrl"`
+	DateCreated   string        `json:"dateCreated"`
+	Title         *string       `json:"title"`
+	Tags          []string      `json:"tags"`
+	VisitsCount   flexibleCount `json:"visitsCount"`
+	VisitsSummary *struct {
+		Total flexibleCount `json:"total"`
+	} `json:"visitsSummary"`
+}
+
+func parseShlinkJSON(data []byte) ([]Record, error) {
+	var response struct {
+		ShortURLs struct {
+			Data []json.RawMessage `json:"data"`
+		} `json:"shortUrls"`
+	}
+	items, err := jsonItems(data, &response, func() []json.RawMessage { return response.ShortURLs.Data })
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeItems(items, func(link shlinkJSONLink) Record {
+		record := Record{
+			Slug:       strings.TrimSpace(link.ShortCode),
+			RedirectTo: strings.TrimSpace(link.LongURL),
+			Title:      stringValue(link.Title),
+			Tags:       link.Tags,
+		}
+		// Shlink 3 reports visits in visitsSummary, older versions in visitsCount
+		visits := link.VisitsCount
+		if link.VisitsSummary != nil {
+			visits = link.VisitsSummary.Total
+		}
+		return withTimeAndVisits(record, link.DateCreated, visits)
+	}), nil
+}
+
+// bitlyJSONLink is a bitlink of the Bitly API (/v4/groups/{group_guid}/bitlinks), which doesn't report clicks
+type bitlyJSONLink struct {
+	ID        string   `json:"id"`
+	Link      string   `json:"link"`
+	LongURL   string   `json:"long_url"`
+	CreatedAt string   `json:"created_at"`
+	Title     *string  `json:"title"`
+	Tags      []string `json:"tags"`
+}
+
+func parseBitlyJSON(data []byte) ([]Record, error) {
+	var response struct {
+		Links []json.RawMessage `json:"links"`
+	}
+	items, err := jsonItems(data, &response, func() []json.RawMessage { return response.Links })
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeItems(items, func(link bitlyJSONLink) Record {
+		shortURL := link.Link
+		if shortURL == "" {
+			shortURL = link.ID
+		}
+		record := Record{
+			Slug:       slugFromShortURL(shortURL),
+			RedirectTo: strings.TrimSpace(link.LongURL),
+			Title:      stringValue(link.Title),
+			Tags:       link.Tags,
+		}
+		return withTimeAndVisits(record, link.CreatedAt, "")
+	}), nil
+}
+
+// stringValue returns the trimmed value of an optional string
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
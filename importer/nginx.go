@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// nginxMapDirectives are the parameters of a map block that aren't entries
+var nginxMapDirectives = map[string]bool{
+	"default":   true,
+	"hostnames": true,
+	"include":   true,
+	"volatile":  true,
+}
+
+// parseNginxMap reads the entries of an nginx map file, such as
+//
+//	map $uri $redirect {
+//	    /docs https://docs.example.com;
+//	}
+//
+// or a file with only the entries, as included by a map block. The path without its leading slash is the slug.
+func parseNginxMap(data []byte) ([]Record, error) {
+	var records []Record
+	for i, line := range strings.Split(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\n") {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line == "{" || line == "}" || strings.HasPrefix(line, "map ") {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimSuffix(line, ";"))
+		if len(fields) > 0 && nginxMapDirectives[fields[0]] {
+			continue
+		}
+
+		record := Record{Position: i + 1}
+		if len(fields) != 2 || !strings.HasSuffix(line, ";") {
+			record.Err = errors.New("expected a path and a destination ending with ';'")
+			records = append(records, record)
+			continue
+		}
+
+		key, value := unquoteNginx(fields[0]), unquoteNginx(fields[1])
+		record.Slug = strings.Trim(key, "/")
+		record.RedirectTo = value
+		if strings.HasPrefix(key, "~") {
+			record.Err = errors.New("regular expression entries can't be imported")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// unquoteNginx removes the quotes around an nginx parameter
+func unquoteNginx(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -1,7 +1,47 @@
 package lib
 
+import "time"
+
 const ROOT_USER_NAME = "Root User"
 
+// REDIRECT_STATUS_CODES are the HTTP status codes a link is allowed to redirect with
+var REDIRECT_STATUS_CODES = map[int]bool{
+	301: true, // Moved Permanently
+	302: true, // Found
+	307: true, // Temporary Redirect
+	308: true, // Permanent Redirect
+}
+
+// DEFAULT_REDIRECT_STATUS_CODE is used when neither the link nor the server configures a redirect status code
+const DEFAULT_REDIRECT_STATUS_CODE = 301
+
+// DEFAULT_BULK_SHORTEN_LIMIT is the number of links a bulk shorten request may create when BULK_SHORTEN_LIMIT isn't set
+const DEFAULT_BULK_SHORTEN_LIMIT = 500
+
+// MAX_BULK_LINK_CHANGES is the number of links a bulk update or delete may change at once
+const MAX_BULK_LINK_CHANGES = 5000
+
+// MAX_IMPORT_LINKS is the number of links an import through the API may contain, the import command has no limit
+const MAX_IMPORT_LINKS = 10000
+
+// QR_SOURCE_PARAM=QR_SOURCE_VALUE is added to URLs encoded in QR codes when attribution is requested, visits record it as their source
+const QR_SOURCE_PARAM = "src"
+const QR_SOURCE_VALUE = "qr"
+
+// UNLOCK_COOKIE_TTL is how long a browser can skip the password form after unlocking a link
+const UNLOCK_COOKIE_TTL = 24 * time.Hour
+
+// UNLOCK_MAX_FAILED_ATTEMPTS wrong passwords within UNLOCK_FAILED_ATTEMPTS_WINDOW block further unlock attempts from an IP
+const UNLOCK_MAX_FAILED_ATTEMPTS = 5
+const UNLOCK_FAILED_ATTEMPTS_WINDOW = 15 * time.Minute
+
+// VISITOR_COOKIE_NAME holds a random visitor ID, so that links with sticky variants send returning visitors to the same variant
+const VISITOR_COOKIE_NAME = "visitor_id"
+const VISITOR_COOKIE_TTL = 365 * 24 * time.Hour
+
+// MAX_VARIANT_WEIGHT is the highest weight a link variant can have
+const MAX_VARIANT_WEIGHT = 10000
+
 type Errors struct {
 	Database                string
 	NoSecretKey             string
@@ -13,6 +53,7 @@ type Errors struct {
 	KeyNameRequired         string
 	KeyNameAlreadyExists    string
 	NewKeyNameTooLong       string
+	DisplayNameTooLong      string
 	CannotUpdateRootUserKey string
 	FailedKeyCreation       string
 }
@@ -28,6 +69,7 @@ var ERRORS = Errors{
 	KeyNameRequired:         "key name required",
 	KeyNameAlreadyExists:    "key name already exists",
 	NewKeyNameTooLong:       "new key name is too long",
+	DisplayNameTooLong:      "display name is too long",
 	CannotUpdateRootUserKey: "cannot update root user key",
 	FailedKeyCreation:       "failed to create new key",
 }
@@ -7,9 +7,13 @@ type Routes struct {
 	V1           string
 	Keys         keysRoutes
 	Links        linksRoutes
+	Audit        auditRoutes
+	Domains      domainsRoutes
+	Namespaces   namespacesRoutes
 	Docs         string
 	DocsJsonFile string
 	NotFound     string
+	Preview      string
 }
 
 type keysRoutes struct {
@@ -22,13 +26,56 @@ type keysRoutes struct {
 }
 
 type linksRoutes struct {
-	Base             string
-	RetrieveAll      string
-	RetrieveAllByKey string
-	Shorten          string
-	Retrieve         string
-	Delete           string
-	Update           string
+	Base                  string
+	RetrieveAll           string
+	RetrieveAllByKey      string
+	Shorten               string
+	BulkShorten           string
+	BulkUpdate            string
+	BulkDelete            string
+	Retrieve              string
+	Delete                string
+	Update                string
+	Trash                 string
+	Restore               string
+	Purge                 string
+	Revisions             string
+	Rollback              string
+	HistoricalSlugs       string
+	ReleaseHistoricalSlug string
+	AddAlias              string
+	RemoveAlias           string
+	QR                    string
+	SetRules              string
+	AddVariant            string
+	UpdateVariant         string
+	RemoveVariant         string
+	ReweightVariants      string
+	VariantStats          string
+	TagStats              string
+	Search                string
+	Import                string
+}
+
+type domainsRoutes struct {
+	Base        string
+	RetrieveAll string
+	Create      string
+	Update      string
+	Delete      string
+}
+
+type namespacesRoutes struct {
+	Base        string
+	RetrieveAll string
+	Create      string
+	Update      string
+	Delete      string
+}
+
+type auditRoutes struct {
+	Base     string
+	Retrieve string
 }
 
 var ROUTES = Routes{
@@ -45,27 +92,70 @@ var ROUTES = Routes{
 		Delete:      "/delete",
 	},
 	Links: linksRoutes{
-		Base:             "/links",
-		RetrieveAll:      "/retrieve-all",
-		RetrieveAllByKey: "/retrieve-all-by-key",
-		Shorten:          "/shorten",
-		Retrieve:         "/retrieve",
-		Delete:           "/delete",
-		Update:           "/update",
+		Base:                  "/links",
+		RetrieveAll:           "/retrieve-all",
+		RetrieveAllByKey:      "/retrieve-all-by-key",
+		Shorten:               "/shorten",
+		BulkShorten:           "/bulk-shorten",
+		BulkUpdate:            "/bulk-update",
+		BulkDelete:            "/bulk-delete",
+		Retrieve:              "/retrieve",
+		Delete:                "/delete",
+		Update:                "/update",
+		Trash:                 "/trash",
+		Restore:               "/restore",
+		Purge:                 "/purge",
+		Revisions:             "/revisions",
+		Rollback:              "/rollback",
+		HistoricalSlugs:       "/historical-slugs",
+		ReleaseHistoricalSlug: "/release-historical-slug",
+		AddAlias:              "/add-alias",
+		RemoveAlias:           "/remove-alias",
+		QR:                    "/qr",
+		SetRules:              "/set-rules",
+		AddVariant:            "/add-variant",
+		UpdateVariant:         "/update-variant",
+		RemoveVariant:         "/remove-variant",
+		ReweightVariants:      "/reweight-variants",
+		VariantStats:          "/variant-stats",
+		TagStats:              "/tag-stats",
+		Search:                "/search",
+		Import:                "/import",
+	},
+	Domains: domainsRoutes{
+		Base:        "/domains",
+		RetrieveAll: "/retrieve-all",
+		Create:      "/create",
+		Update:      "/update",
+		Delete:      "/delete",
+	},
+	Namespaces: namespacesRoutes{
+		Base:        "/namespaces",
+		RetrieveAll: "/retrieve-all",
+		Create:      "/create",
+		Update:      "/update",
+		Delete:      "/delete",
+	},
+	Audit: auditRoutes{
+		Base:     "/audit",
+		Retrieve: "/retrieve",
 	},
 	Docs:         "/docs",
 	DocsJsonFile: "/docs/doc.json",
 	NotFound:     "/404",
+	Preview:      "/preview",
 }
 
 type ReservedRoutes struct {
 	API      string
 	Docs     string
 	NotFound string
+	Preview  string
 }
 
 var RESERVED_ROUTES = ReservedRoutes{
 	API:      "api",
 	Docs:     "docs",
 	NotFound: "404",
+	Preview:  "preview",
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"go-link-shortener/database"
 	_ "go-link-shortener/docs"
@@ -39,6 +40,11 @@ import (
 // @name Authorization
 // @description API key authentication. Add 'Authorization' header with your API key.
 func main() {
+	// "go-link-shortener import ..." imports links instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+
 	log.Println("Starting Link Shortener")
 
 	log.Println("⏳ Loading environment variables...")
@@ -54,6 +60,15 @@ func main() {
 
 	models.InitializeRootUser(database.GetDB(), env.ROOT_USER_KEY)
 
+	// without UNLOCK_COOKIE_SECRET, unlock cookies are signed with a random secret kept in the database
+	if env.UNLOCK_COOKIE_SECRET == "" {
+		secret, err := models.RetrieveServerSecret(database.GetDB(), models.ServerSecretUnlockCookie)
+		if err != nil {
+			log.Fatal(err)
+		}
+		utils.SetGeneratedUnlockCookieSecret(secret)
+	}
+
 	log.Println("⏳ Setting up background workers...")
 
 	// Initialize the link expiration worker
@@ -67,6 +82,17 @@ func main() {
 		}
 	}()
 
+	// Initialize the trash purge worker, unless purging is disabled
+	if env.TRASH_RETENTION_DAYS > 0 {
+		trashWorker := workers.NewTrashPurgeWorker(database.GetDB(), env.TRASH_RETENTION_DAYS)
+
+		go func() {
+			if err := trashWorker.Start(ctx); err != nil {
+				log.Printf("Trash purge worker error: %v", err)
+			}
+		}()
+	}
+
 	log.Println("✔️  Background workers set up successfully.")
 
 	// Spin up the webserver
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FindLinkAlias searches a domain and namespace for a link alias by its slug
+func FindLinkAlias(db *gorm.DB, scope SlugScope, slug string) (*LinkAlias, error) {
+	var alias LinkAlias
+	if err := db.Scopes(scope.Apply).Where("slug = ?", slug).First(&alias).Error; err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// AddLinkAlias adds an alias slug to a link, in the link's domain and namespace
+func AddLinkAlias(db *gorm.DB, linkID uuid.UUID, scope SlugScope, slug string, createdBy uuid.UUID) (*LinkAlias, error) {
+	alias := &LinkAlias{
+		LinkID:      linkID,
+		DomainID:    scope.DomainID,
+		NamespaceID: scope.NamespaceID,
+		Slug:        slug,
+		CreatedBy:   createdBy,
+	}
+	if err := db.Create(alias).Error; err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+// RemoveLinkAlias removes an alias slug from a link
+func RemoveLinkAlias(db *gorm.DB, linkID uuid.UUID, slug string) (bool, error) {
+	result := db.Where("link_id = ? AND slug = ?", linkID, slug).Delete(&LinkAlias{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/json"
+	"go-link-shortener/database"
+	"go-link-shortener/lib"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditFieldChange holds the before and after value of a single changed field
+type AuditFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEventFilter narrows down the audit events returned by RetrieveAuditEvents
+type AuditEventFilter struct {
+	ActorKeyID *uuid.UUID
+	Target     string
+	From       *time.Time
+	To         *time.Time
+}
+
+// auditEventSorts are the fields audit events can be sorted by
+var auditEventSorts = map[string]sortColumn[AuditEvent]{
+	"timestamp": {Expr: "audit_events.timestamp", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(e AuditEvent) interface{} { return e.Timestamp }},
+}
+
+// KeyAuditSnapshot returns the audited fields of a secret key.
+// The key value itself is never included so that secrets don't end up in the audit trail.
+func KeyAuditSnapshot(key SecretKey) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         key.Name,
+		"is_active":    key.IsActive,
+		"is_admin":     key.IsAdmin,
+		"fallback_url": key.FallbackURL,
+		"display_name": key.DisplayName,
+	}
+}
+
+// LinkAuditSnapshot returns the audited fields of a link
+func LinkAuditSnapshot(link Link) map[string]interface{} {
+	return map[string]interface{}{
+		"shortened":          link.Shortened,
+		"redirect_to":        link.RedirectTo,
+		"expires_at":         auditTime(link.ExpiresAt),
+		"is_active":          link.IsActive,
+		"redirect_type":      link.RedirectType,
+		"has_password":       link.PasswordHash != nil,
+		"max_visits":         link.MaxVisits,
+		"starts_at":          auditTime(link.StartsAt),
+		"prelaunch_url":      link.PrelaunchURL,
+		"fallback_url":       link.FallbackURL,
+		"sticky_variants":    link.StickyVariants,
+		"forward_query":      link.ForwardQuery,
+		"forward_query_keys": link.ForwardQueryKeys,
+		"path_mode":          link.PathMode,
+		"domain_id":          link.DomainID,
+		"namespace_id":       link.NamespaceID,
+		"tags":               TagNames(link.Tags),
+		"folder":             link.Folder,
+		"title":              link.Title,
+		"notes":              link.Notes,
+		"created_by":         link.CreatedBy.String(),
+	}
+}
+
+// auditTime converts an optional timestamp to a comparable value
+func auditTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// BuildAuditDiff compares two snapshots and returns only the fields that changed.
+// A nil before snapshot describes a creation, a nil after snapshot describes a deletion.
+func BuildAuditDiff(before, after map[string]interface{}) map[string]AuditFieldChange {
+	fields := make(map[string]struct{})
+	for field := range before {
+		fields[field] = struct{}{}
+	}
+	for field := range after {
+		fields[field] = struct{}{}
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	diff := make(map[string]AuditFieldChange)
+	for _, field := range names {
+		beforeValue, afterValue := before[field], after[field]
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		diff[field] = AuditFieldChange{Before: beforeValue, After: afterValue}
+	}
+
+	return diff
+}
+
+// CreateAuditEvent stores an audit event with the diff between the given snapshots.
+// Like CreateLog, failures are logged rather than returned so auditing never blocks a request.
+func CreateAuditEvent(event AuditEvent, before, after map[string]interface{}) {
+	db := database.GetDB()
+	if db == nil {
+		log.Println(lib.ERRORS.Database)
+		return
+	}
+
+	diff, err := json.Marshal(BuildAuditDiff(before, after))
+	if err != nil {
+		log.Println("Error encoding audit diff:", err)
+		return
+	}
+
+	event.Diff = string(diff)
+	event.Timestamp = time.Now()
+	if event.SourceIP != nil && *event.SourceIP == "" {
+		event.SourceIP = nil
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		log.Println("Error creating audit event:", err)
+	}
+}
+
+// RetrieveAuditEvents returns a page of the audit events matching the filter, newest first by default
+func RetrieveAuditEvents(db *gorm.DB, filter AuditEventFilter, params ListParams) ([]AuditEvent, PageInfo, error) {
+	query := db.Model(&AuditEvent{})
+
+	if filter.ActorKeyID != nil {
+		query = query.Where("actor_key_id = ?", *filter.ActorKeyID)
+	}
+	if filter.Target != "" {
+		query = query.Where("(target_id = ? OR target_name = ?)", filter.Target, filter.Target)
+	}
+	if filter.From != nil {
+		query = query.Where("timestamp >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("timestamp <= ?", *filter.To)
+	}
+
+	return paginate(query, "audit_events", params, auditEventSorts, "timestamp",
+		func(e AuditEvent) uuid.UUID { return e.ID })
+}
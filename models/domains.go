@@ -0,0 +1,95 @@
+package models
+
+import (
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NormalizeHost lowercases a host name and strips its port and trailing dot, so that it can be compared to Domain.Host
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// FindDomainByHost searches for a domain by its host name
+func FindDomainByHost(db *gorm.DB, host string) (*Domain, error) {
+	var domain Domain
+	if err := db.Preload("AllowedKeys").Where("host = ?", NormalizeHost(host)).First(&domain).Error; err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// domainSorts are the fields domain lists can be sorted by
+var domainSorts = map[string]sortColumn[Domain]{
+	"host": {Expr: "domains.host", Kind: sortString, DefaultOrder: "asc",
+		Value: func(d Domain) interface{} { return d.Host }},
+	"created_at": {Expr: "domains.created_at", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(d Domain) interface{} { return d.CreatedAt }},
+}
+
+// RetrieveAllDomains returns a page of the domains, sorted by host by default
+func RetrieveAllDomains(db *gorm.DB, params ListParams) ([]Domain, PageInfo, error) {
+	return paginate(db.Model(&Domain{}), "domains", params, domainSorts, "host",
+		func(d Domain) uuid.UUID { return d.ID },
+		func(db *gorm.DB) *gorm.DB { return db.Preload("AllowedKeys") })
+}
+
+// RetrieveDomainHosts returns the host names of every domain
+func RetrieveDomainHosts(db *gorm.DB) ([]string, error) {
+	var hosts []string
+	if err := db.Model(&Domain{}).Pluck("host", &hosts).Error; err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// SaveDomain creates or updates a domain and replaces its allowed keys
+func SaveDomain(db *gorm.DB, domain *Domain, allowedKeys []SecretKey) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("AllowedKeys").Save(domain).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(domain).Association("AllowedKeys").Replace(allowedKeys); err != nil {
+			return err
+		}
+		domain.AllowedKeys = allowedKeys
+		return nil
+	})
+}
+
+// DeleteDomain deletes a domain and its allowed keys
+func DeleteDomain(db *gorm.DB, domain *Domain) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(domain).Association("AllowedKeys").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(domain).Error
+	})
+}
+
+// CountDomainLinks counts the links on a domain, including trashed ones
+func CountDomainLinks(db *gorm.DB, domainID uuid.UUID) (int64, error) {
+	var count int64
+	err := db.Unscoped().Model(&Link{}).Where("domain_id = ?", domainID).Count(&count).Error
+	return count, err
+}
+
+// DomainAllowsKey reports whether a key may create links on a domain
+func DomainAllowsKey(domain Domain, keyID uuid.UUID) bool {
+	if len(domain.AllowedKeys) == 0 {
+		return true
+	}
+	for _, key := range domain.AllowedKeys {
+		if key.ID == keyID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FindHistoricalSlug searches a domain and namespace for a historical slug by its slug
+func FindHistoricalSlug(db *gorm.DB, scope SlugScope, slug string) (*HistoricalSlug, error) {
+	var historicalSlug HistoricalSlug
+	if err := db.Scopes(scope.Apply).Where("slug = ?", slug).First(&historicalSlug).Error; err != nil {
+		return nil, err
+	}
+	return &historicalSlug, nil
+}
+
+// RetrieveHistoricalSlugs returns the historical slugs of a link, newest first
+func RetrieveHistoricalSlugs(db *gorm.DB, linkID uuid.UUID) ([]HistoricalSlug, error) {
+	var historicalSlugs []HistoricalSlug
+	if err := db.Where("link_id = ?", linkID).Order("created_at DESC").Find(&historicalSlugs).Error; err != nil {
+		return nil, err
+	}
+	return historicalSlugs, nil
+}
+
+// RecordHistoricalSlug keeps the old slug of a renamed link reserved and redirecting to it
+func RecordHistoricalSlug(db *gorm.DB, linkID uuid.UUID, scope SlugScope, slug string, redirectToCurrent bool) error {
+	return db.Create(&HistoricalSlug{
+		LinkID:            linkID,
+		DomainID:          scope.DomainID,
+		NamespaceID:       scope.NamespaceID,
+		Slug:              slug,
+		RedirectToCurrent: redirectToCurrent,
+	}).Error
+}
+
+// ReleaseHistoricalSlug deletes a historical slug of a link, making it available again
+func ReleaseHistoricalSlug(db *gorm.DB, linkID uuid.UUID, slug string) (bool, error) {
+	result := db.Where("link_id = ? AND slug = ?", linkID, slug).Delete(&HistoricalSlug{})
+	return result.RowsAffected > 0, result.Error
+}
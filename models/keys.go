@@ -8,6 +8,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -102,8 +103,55 @@ func SearchKeyByKey(db *gorm.DB, key string) *SecretKey {
 	return &secretKey
 }
 
-func RetrieveAllKeys(db *gorm.DB) []SecretKey {
-	var secretKeys []SecretKey
-	db.Find(&secretKeys)
-	return secretKeys
+func SearchKeyByID(db *gorm.DB, id uuid.UUID) *SecretKey {
+	var secretKey SecretKey
+	result := db.Where("id = ?", id).First(&secretKey)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		// No record found, return nil without logging the error
+		return nil
+	}
+
+	if result.Error != nil {
+		log.Printf("Error querying database: %v", result.Error)
+		return nil
+	}
+
+	return &secretKey
+}
+
+// KeyFilter narrows down key lists, empty fields don't filter
+type KeyFilter struct {
+	IsActive    *bool
+	IsAdmin     *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// keySorts are the fields key lists can be sorted by
+var keySorts = map[string]sortColumn[SecretKey]{
+	"created_at": {Expr: "secret_keys.created_at", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(k SecretKey) interface{} { return k.CreatedAt }},
+	"name": {Expr: "secret_keys.name", Kind: sortString, DefaultOrder: "asc",
+		Value: func(k SecretKey) interface{} { return k.Name }},
+}
+
+// RetrieveAllKeys returns a page of the keys matching the filter
+func RetrieveAllKeys(db *gorm.DB, filter KeyFilter, params ListParams) ([]SecretKey, PageInfo, error) {
+	query := db.Model(&SecretKey{})
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.IsAdmin != nil {
+		query = query.Where("is_admin = ?", *filter.IsAdmin)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *filter.CreatedTo)
+	}
+
+	return paginate(query, "secret_keys", params, keySorts, "created_at",
+		func(k SecretKey) uuid.UUID { return k.ID })
 }
@@ -2,9 +2,14 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Find an active link by shortened URL
@@ -20,23 +25,299 @@ func FindActiveLink(db *gorm.DB, shortened string) (*Link, error) {
 	return &link, nil
 }
 
-func RetrieveAllLinks(db *gorm.DB) []Link {
-	var links []Link
-	db.Preload("SecretKey").Find(&links)
-	return links
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// LinkStatus filters links by whether they currently redirect
+type LinkStatus string
+
+const (
+	// active links are enabled and not expired
+	LinkStatusActive LinkStatus = "active"
+	// inactive links were deactivated, manually or by reaching max_visits
+	LinkStatusInactive LinkStatus = "inactive"
+	// expired links are past their expires_at
+	LinkStatusExpired LinkStatus = "expired"
+)
+
+// destinationHostExpr extracts the lowercase host of a link's redirect_to
+const destinationHostExpr = `LOWER(SUBSTRING(links.redirect_to FROM '^[^:]+://(?:[^@/?#]*@)?([^/?#:]+)'))`
+
+// LinkFilter narrows down link lists, empty fields don't filter
+type LinkFilter struct {
+	// Tag only keeps links with this tag
+	Tag string
+	// Folder only keeps links in this folder or one of its subfolders
+	Folder string
+	Status LinkStatus
+	// CreatedFrom and CreatedTo limit the creation time, ExpiresFrom and ExpiresTo the expiry, both ends inclusive
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	ExpiresFrom *time.Time
+	ExpiresTo   *time.Time
+	// DestinationDomain only keeps links redirecting to this host or one of its subdomains
+	DestinationDomain string
+	// CreatedBy only keeps links created by this key
+	CreatedBy *uuid.UUID
+	// ManageableBy only keeps links this key created or that live in a namespace it owns
+	ManageableBy *uuid.UUID
+}
+
+// Apply scopes a query on links to the filter
+func (f LinkFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f.Tag != "" {
+		db = db.Where("links.id IN (?)", db.Session(&gorm.Session{NewDB: true}).Table("link_tags").
+			Select("link_tags.link_id").Joins("JOIN tags ON tags.id = link_tags.tag_id").Where("tags.name = ?", f.Tag))
+	}
+	if f.Folder != "" {
+		db = db.Where("(links.folder = ? OR links.folder LIKE ?)", f.Folder, escapeLike(f.Folder)+"/%")
+	}
+
+	now := time.Now()
+	switch f.Status {
+	case LinkStatusActive:
+		db = db.Where("links.is_active = ? AND (links.expires_at IS NULL OR links.expires_at > ?)", true, now)
+	case LinkStatusInactive:
+		db = db.Where("links.is_active = ?", false)
+	case LinkStatusExpired:
+		db = db.Where("links.expires_at <= ?", now)
+	}
+
+	if f.CreatedFrom != nil {
+		db = db.Where("links.created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		db = db.Where("links.created_at <= ?", *f.CreatedTo)
+	}
+	if f.ExpiresFrom != nil {
+		db = db.Where("links.expires_at >= ?", *f.ExpiresFrom)
+	}
+	if f.ExpiresTo != nil {
+		db = db.Where("links.expires_at <= ?", *f.ExpiresTo)
+	}
+
+	if f.DestinationDomain != "" {
+		host := strings.ToLower(f.DestinationDomain)
+		db = db.Where(fmt.Sprintf("(%s = ? OR %s LIKE ?)", destinationHostExpr, destinationHostExpr), host, "%."+escapeLike(host))
+	}
+
+	if f.CreatedBy != nil {
+		db = db.Where("links.created_by = ?", *f.CreatedBy)
+	}
+	if f.ManageableBy != nil {
+		db = db.Where("(links.created_by = ? OR links.namespace_id IN (?))", *f.ManageableBy,
+			db.Session(&gorm.Session{NewDB: true}).Model(&Namespace{}).Select("id").Where("owner_id = ?", *f.ManageableBy))
+	}
+	return db
+}
+
+// linkSorts are the fields link lists can be sorted by, links that were never visited sort as visited at the epoch
+var linkSorts = map[string]sortColumn[Link]{
+	"created_at": {Expr: "links.created_at", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(l Link) interface{} { return l.CreatedAt }},
+	"visits": {Expr: "links.visits", Kind: sortInt, DefaultOrder: "desc",
+		Value: func(l Link) interface{} { return l.Visits }},
+	"last_visited_at": {Expr: "COALESCE(links.last_visited_at, TIMESTAMPTZ '1970-01-01 00:00:00+00')", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(l Link) interface{} {
+			if l.LastVisitedAt == nil {
+				return time.Unix(0, 0)
+			}
+			return *l.LastVisitedAt
+		}},
+}
+
+// trashSorts also sort trashed links by the time they were trashed
+var trashSorts = map[string]sortColumn[Link]{
+	"created_at":      linkSorts["created_at"],
+	"visits":          linkSorts["visits"],
+	"last_visited_at": linkSorts["last_visited_at"],
+	"deleted_at": {Expr: "links.deleted_at", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(l Link) interface{} { return l.DeletedAt.Time }},
+}
+
+func linkID(l Link) uuid.UUID {
+	return l.ID
+}
+
+// preloadLink loads the relationships of listed links
+func preloadLink(db *gorm.DB) *gorm.DB {
+	return db.Preload("SecretKey").Preload("Aliases").Preload("Rules", OrderLinkRules).Preload("Variants", OrderLinkVariants).
+		Preload("Domain").Preload("Namespace").Preload("Tags", OrderTags)
+}
+
+// RetrieveAllLinks returns a page of the links matching the filter
+func RetrieveAllLinks(db *gorm.DB, filter LinkFilter, params ListParams) ([]Link, PageInfo, error) {
+	return paginate(db.Model(&Link{}).Scopes(filter.Apply), "links", params, linkSorts, "created_at", linkID, preloadLink)
 }
 
-func RetrieveAllLinksByKey(db *gorm.DB, key string) ([]Link, error) {
+// RetrieveAllLinksByKey returns a page of the links created by a key that match the filter
+func RetrieveAllLinksByKey(db *gorm.DB, key string, filter LinkFilter, params ListParams) ([]Link, PageInfo, error) {
 	// retrieve the UUID associated with the key
 	var secretKey SecretKey
 	if err := db.Where("key = ?", key).First(&secretKey).Error; err != nil {
-		return nil, errors.New("key not found")
+		return nil, PageInfo{}, errors.New("key not found")
 	}
 
+	query := db.Model(&Link{}).Scopes(filter.Apply).Where("links.created_by = ?", secretKey.ID)
+	return paginate(query, "links", params, linkSorts, "created_at", linkID, preloadLink)
+}
+
+// RetrieveFilteredLinks returns the links matching the filter, oldest first. At most limit+1 links are returned,
+// so that callers can tell whether the filter matched more than limit links.
+func RetrieveFilteredLinks(db *gorm.DB, filter LinkFilter, limit int) ([]Link, error) {
 	var links []Link
-	if err := db.Preload("SecretKey").Where("created_by = ?", secretKey.ID).Find(&links).Error; err != nil {
-		return nil, errors.New("failed to retrieve links by key")
+	err := db.Scopes(filter.Apply, preloadLink).Order("links.created_at ASC, links.id ASC").Limit(limit + 1).Find(&links).Error
+	if err != nil {
+		return nil, err
 	}
-
 	return links, nil
 }
+
+// RetrieveTrashedLinks returns a page of the links in the trash that match the filter
+func RetrieveTrashedLinks(db *gorm.DB, filter LinkFilter, params ListParams) ([]Link, PageInfo, error) {
+	query := db.Unscoped().Model(&Link{}).Scopes(filter.Apply).Where("links.deleted_at IS NOT NULL")
+	return paginate(query, "links", params, trashSorts, "deleted_at", linkID, preloadLink)
+}
+
+// RetrieveTrashedLink searches the trash of a domain and namespace for a link by its shortened URL
+func RetrieveTrashedLink(db *gorm.DB, scope SlugScope, shortened string) (*Link, error) {
+	var link Link
+	result := db.Unscoped().Preload("SecretKey").Preload("Aliases").Preload("Rules", OrderLinkRules).Preload("Variants", OrderLinkVariants).Preload("Domain").Preload("Namespace").Preload("Tags", OrderTags).
+		Scopes(scope.Apply).Where("shortened = ? AND deleted_at IS NOT NULL", shortened).First(&link)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &link, nil
+}
+
+// RestoreLink moves a trashed link back out of the trash
+func RestoreLink(db *gorm.DB, link *Link) error {
+	if err := db.Unscoped().Model(link).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	link.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// PurgeLink permanently deletes a link along with its visit and revision history, historical slugs, aliases, rules, variants and tag assignments
+func PurgeLink(db *gorm.DB, link *Link) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", link.ID).Delete(&LinkVisit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&LinkRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&HistoricalSlug{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&LinkAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&LinkRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&LinkVariant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM link_tags WHERE link_id = ?", link.ID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(link).Error
+	})
+}
+
+// PurgeTrashedLinksBefore permanently deletes every link that was moved to the trash before the cutoff.
+// Returns the number of purged links.
+func PurgeTrashedLinksBefore(db *gorm.DB, cutoff time.Time) (int64, error) {
+	var purged int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&Link{}).Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+
+		if err := tx.Where("link_id IN (?)", expired).Delete(&LinkVisit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id IN (?)", expired).Delete(&LinkRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id IN (?)", expired).Delete(&HistoricalSlug{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id IN (?)", expired).Delete(&LinkAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id IN (?)", expired).Delete(&LinkRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id IN (?)", expired).Delete(&LinkVariant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM link_tags WHERE link_id IN (?)", expired).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&Link{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+		return nil
+	})
+
+	return purged, err
+}
+
+// linkEditableColumns are the columns of a link its owners can change. Visits and last_visited_at are left out,
+// they are only changed by visits, which may happen while a link is being edited.
+var linkEditableColumns = []struct {
+	Column string
+	Value  func(Link) interface{}
+}{
+	{"redirect_to", func(l Link) interface{} { return l.RedirectTo }},
+	{"shortened", func(l Link) interface{} { return l.Shortened }},
+	{"expires_at", func(l Link) interface{} { return l.ExpiresAt }},
+	{"is_active", func(l Link) interface{} { return l.IsActive }},
+	{"redirect_type", func(l Link) interface{} { return l.RedirectType }},
+	{"password_hash", func(l Link) interface{} { return l.PasswordHash }},
+	{"max_visits", func(l Link) interface{} { return l.MaxVisits }},
+	{"starts_at", func(l Link) interface{} { return l.StartsAt }},
+	{"prelaunch_url", func(l Link) interface{} { return l.PrelaunchURL }},
+	{"fallback_url", func(l Link) interface{} { return l.FallbackURL }},
+	{"sticky_variants", func(l Link) interface{} { return l.StickyVariants }},
+	{"forward_query", func(l Link) interface{} { return l.ForwardQuery }},
+	{"forward_query_keys", func(l Link) interface{} { return l.ForwardQueryKeys }},
+	{"path_mode", func(l Link) interface{} { return l.PathMode }},
+	{"domain_id", func(l Link) interface{} { return l.DomainID }},
+	{"namespace_id", func(l Link) interface{} { return l.NamespaceID }},
+	{"folder", func(l Link) interface{} { return l.Folder }},
+	{"title", func(l Link) interface{} { return l.Title }},
+	{"notes", func(l Link) interface{} { return l.Notes }},
+}
+
+// ChangedLinkColumns returns the editable columns whose value differs between two versions of a link
+func ChangedLinkColumns(before, after Link) []string {
+	var columns []string
+	for _, editable := range linkEditableColumns {
+		if !reflect.DeepEqual(editable.Value(before), editable.Value(after)) {
+			columns = append(columns, editable.Column)
+		}
+	}
+	return columns
+}
+
+// SaveLinkChanges writes the columns changed from before to after. Unlike Save, it doesn't write back
+// the visits counted since the link was loaded, nor the relationships that were loaded with it.
+func SaveLinkChanges(db *gorm.DB, before Link, after *Link) error {
+	columns := ChangedLinkColumns(before, *after)
+	if len(columns) == 0 {
+		return nil
+	}
+	after.UpdatedAt = time.Now()
+	return db.Model(after).Select(append(columns, "updated_at")).Omit(clause.Associations).Updates(after).Error
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SlugScope is where a slug is unique: a domain, nil being the default domain, and a namespace, nil for top-level slugs
+type SlugScope struct {
+	DomainID    *uuid.UUID
+	NamespaceID *uuid.UUID
+}
+
+// LinkSlugScope returns the scope of a link's slug, which its aliases and historical slugs share
+func LinkSlugScope(link Link) SlugScope {
+	return SlugScope{DomainID: link.DomainID, NamespaceID: link.NamespaceID}
+}
+
+// Apply scopes a query on links, link_aliases or historical_slugs to the slug scope
+func (s SlugScope) Apply(db *gorm.DB) *gorm.DB {
+	if s.DomainID == nil {
+		db = db.Where("domain_id IS NULL")
+	} else {
+		db = db.Where("domain_id = ?", *s.DomainID)
+	}
+	if s.NamespaceID == nil {
+		return db.Where("namespace_id IS NULL")
+	}
+	return db.Where("namespace_id = ?", *s.NamespaceID)
+}
+
+// FindNamespace searches for a namespace by its name
+func FindNamespace(db *gorm.DB, name string) (*Namespace, error) {
+	var namespace Namespace
+	if err := db.Preload("Owner").Preload("Members").Where("name = ?", name).First(&namespace).Error; err != nil {
+		return nil, err
+	}
+	return &namespace, nil
+}
+
+// namespaceSorts are the fields namespace lists can be sorted by
+var namespaceSorts = map[string]sortColumn[Namespace]{
+	"name": {Expr: "namespaces.name", Kind: sortString, DefaultOrder: "asc",
+		Value: func(n Namespace) interface{} { return n.Name }},
+	"created_at": {Expr: "namespaces.created_at", Kind: sortTime, DefaultOrder: "desc",
+		Value: func(n Namespace) interface{} { return n.CreatedAt }},
+}
+
+// RetrieveNamespaces returns a page of the namespaces, sorted by name by default,
+// optionally limited to the ones a key owns or is a member of
+func RetrieveNamespaces(db *gorm.DB, keyID *uuid.UUID, params ListParams) ([]Namespace, PageInfo, error) {
+	query := db.Model(&Namespace{})
+	if keyID != nil {
+		query = query.Where("(namespaces.owner_id = ? OR namespaces.id IN (?))", *keyID,
+			db.Table("namespace_members").Select("namespace_id").Where("secret_key_id = ?", *keyID))
+	}
+	return paginate(query, "namespaces", params, namespaceSorts, "name",
+		func(n Namespace) uuid.UUID { return n.ID },
+		func(db *gorm.DB) *gorm.DB { return db.Preload("Owner").Preload("Members") })
+}
+
+// SaveNamespace creates or updates a namespace and replaces its members
+func SaveNamespace(db *gorm.DB, namespace *Namespace, members []SecretKey) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Owner", "Members").Save(namespace).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(namespace).Association("Members").Replace(members); err != nil {
+			return err
+		}
+		namespace.Members = members
+		return nil
+	})
+}
+
+// DeleteNamespace deletes a namespace and its memberships
+func DeleteNamespace(db *gorm.DB, namespace *Namespace) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(namespace).Association("Members").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(namespace).Error
+	})
+}
+
+// CountNamespaceLinks counts the links inside a namespace on every domain, including trashed ones
+func CountNamespaceLinks(db *gorm.DB, namespaceID uuid.UUID) (int64, error) {
+	var count int64
+	err := db.Unscoped().Model(&Link{}).Where("namespace_id = ?", namespaceID).Count(&count).Error
+	return count, err
+}
+
+// IsTopLevelSlugUsed reports whether a top-level slug is used by a link, alias or historical slug on any domain,
+// so that it can't become a namespace
+func IsTopLevelSlugUsed(db *gorm.DB, slug string) (bool, error) {
+	for _, query := range []*gorm.DB{
+		db.Unscoped().Model(&Link{}).Where("shortened = ?", slug),
+		db.Model(&LinkAlias{}).Where("slug = ?", slug),
+		db.Model(&HistoricalSlug{}).Where("slug = ?", slug),
+	} {
+		var count int64
+		if err := query.Where("namespace_id IS NULL").Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NamespaceAllowsKey reports whether a key may create links inside a namespace
+func NamespaceAllowsKey(namespace Namespace, keyID uuid.UUID) bool {
+	if namespace.OwnerID == keyID {
+		return true
+	}
+	for _, member := range namespace.Members {
+		if member.ID == keyID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// ErrInvalidListParams is returned for unknown sort fields, orders or cursors
+var ErrInvalidListParams = errors.New("invalid list parameters")
+
+// ListParams selects a page of a list. Pages are keyset paginated: Cursor is the next_cursor of the previous page,
+// so that rows created or deleted in between don't shift the pages.
+type ListParams struct {
+	// Limit is the page size, 0 uses the default of 100, at most 1000
+	Limit  int
+	Cursor string
+	// Sort is the field to sort by, empty uses the list's default
+	Sort string
+	// Order is "asc" or "desc", empty uses the sort field's default
+	Order string
+}
+
+// PageInfo describes the page of a list
+type PageInfo struct {
+	// Total is the number of rows matching the filters on every page
+	Total int64 `json:"total"`
+	// NextCursor fetches the next page, nil on the last page
+	NextCursor *string `json:"next_cursor"`
+}
+
+type sortKind int
+
+const (
+	sortTime sortKind = iota
+	sortInt
+	sortString
+)
+
+// sortColumn is a field a list of T can be sorted by
+type sortColumn[T any] struct {
+	// Expr is the SQL expression sorted by, it must never be NULL
+	Expr         string
+	Kind         sortKind
+	DefaultOrder string
+	// Value returns the value of Expr for a row, a time.Time, int64 or string depending on Kind
+	Value func(T) interface{}
+}
+
+// pageCursor is the decoded form of PageInfo.NextCursor: the sort value and ID of the last row of a page
+type pageCursor struct {
+	Sort  string    `json:"s"`
+	Order string    `json:"o"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidListParams)
+	}
+	return c, nil
+}
+
+// formatSortValue converts a sort value to its cursor form
+func formatSortValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseSortValue converts the cursor form of a sort value back to the type of its column
+func parseSortValue(kind sortKind, value string) (interface{}, error) {
+	switch kind {
+	case sortTime:
+		return time.Parse(time.RFC3339Nano, value)
+	case sortInt:
+		return strconv.ParseInt(value, 10, 64)
+	default:
+		return value, nil
+	}
+}
+
+// sortNames lists the sort fields of a list for error messages
+func sortNames[T any](sorts map[string]sortColumn[T]) string {
+	names := make([]string, 0, len(sorts))
+	for name := range sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// paginate counts the rows of a filtered query and fetches one page of them.
+// The page is sorted by the chosen column and the table's id as a tie breaker, scopes such as preloads only apply to the fetched rows.
+func paginate[T any](query *gorm.DB, table string, params ListParams, sorts map[string]sortColumn[T], defaultSort string,
+	id func(T) uuid.UUID, scopes ...func(*gorm.DB) *gorm.DB) ([]T, PageInfo, error) {
+	var page PageInfo
+
+	sortName := params.Sort
+	if sortName == "" {
+		sortName = defaultSort
+	}
+	column, ok := sorts[sortName]
+	if !ok {
+		return nil, page, fmt.Errorf("%w: sort must be one of %s", ErrInvalidListParams, sortNames(sorts))
+	}
+
+	order := strings.ToLower(params.Order)
+	if order == "" {
+		order = column.DefaultOrder
+	}
+	if order != "asc" && order != "desc" {
+		return nil, page, fmt.Errorf("%w: order must be asc or desc", ErrInvalidListParams)
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	// every following call clones the filtered query instead of adding to it
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, page, err
+	}
+
+	idExpr := table + ".id"
+	if params.Cursor != "" {
+		cursor, err := decodeCursor(params.Cursor)
+		if err != nil {
+			return nil, page, err
+		}
+		if cursor.Sort != sortName || cursor.Order != order {
+			return nil, page, fmt.Errorf("%w: the cursor belongs to another sort", ErrInvalidListParams)
+		}
+		value, err := parseSortValue(column.Kind, cursor.Value)
+		if err != nil {
+			return nil, page, fmt.Errorf("%w: malformed cursor", ErrInvalidListParams)
+		}
+
+		operator := "<"
+		if order == "asc" {
+			operator = ">"
+		}
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", column.Expr, idExpr, operator), value, cursor.ID)
+	}
+
+	// one extra row tells whether there is a next page
+	var rows []T
+	err := query.Scopes(scopes...).
+		Order(fmt.Sprintf("%s %s, %s %s", column.Expr, order, idExpr, order)).
+		Limit(limit + 1).Find(&rows).Error
+	if err != nil {
+		return nil, page, err
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		next := encodeCursor(pageCursor{Sort: sortName, Order: order, Value: formatSortValue(column.Value(last)), ID: id(last)})
+		page.NextCursor = &next
+	}
+
+	return rows, page, nil
+}
@@ -1,37 +1,92 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// VisitDetails describes a single visit to a link
+type VisitDetails struct {
+	Slug      string
+	UserAgent string
+	IPAddress string
+	Referrer  string
+	Source    string
+	RuleID    *uuid.UUID
+	VariantID *uuid.UUID
+}
+
+// optionalString returns nil for empty strings so that optional columns stay NULL
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ErrVisitLimitReached is returned when a link has already been visited max_visits times
+var ErrVisitLimitReached = errors.New("link has reached its visit limit")
+
+// ErrVisitNotLogged is returned when a visit was counted but its visit record couldn't be created
+var ErrVisitNotLogged = errors.New("visit was counted but couldn't be logged")
+
 // Record a visit
-func RecordVisit(db *gorm.DB, linkID uuid.UUID, userAgent, ipAddress, referrer string) error {
+// The visit count is only incremented while it is below the link's max_visits, so concurrent visits
+// can't redeem a visit-limited link more often than allowed. ErrVisitLimitReached is returned otherwise.
+// The count is committed before the visit record is created, so a visit that can't be logged still uses up
+// a visit of the link, ErrVisitNotLogged is returned then.
+func RecordVisit(db *gorm.DB, linkID uuid.UUID, details VisitDetails) error {
+	now := time.Now()
 	visit := &LinkVisit{
 		LinkID:    linkID,
-		UserAgent: &userAgent,
-		IPAddress: &ipAddress,
-		Referrer:  &referrer,
+		VisitedAt: now,
+		Slug:      optionalString(details.Slug),
+		UserAgent: optionalString(details.UserAgent),
+		IPAddress: optionalString(details.IPAddress),
+		Referrer:  optionalString(details.Referrer),
+		Source:    optionalString(details.Source),
+		RuleID:    details.RuleID,
+		VariantID: details.VariantID,
+		Type:      VisitTypeRedirect,
+	}
+
+	// Update link visit count and last visited time, the row lock taken here serializes concurrent visits
+	result := db.Model(&Link{}).
+		Where("id = ? AND (max_visits IS NULL OR visits < max_visits)", linkID).
+		Updates(map[string]interface{}{
+			"visits":          gorm.Expr("visits + 1"),
+			"last_visited_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVisitLimitReached
 	}
 
-	// Create visit record and update link visit count in a transaction
-	return db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(visit).Error; err != nil {
-			return err
-		}
-
-		// Update link visit count and last visited time
-		if err := tx.Model(&Link{}).
-			Where("id = ?", linkID).
-			Updates(map[string]interface{}{
-				"visits":          gorm.Expr("visits + 1"),
-				"last_visited_at": time.Now(),
-			}).Error; err != nil {
-			return err
-		}
+	if err := db.Create(visit).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrVisitNotLogged, err)
+	}
+	return nil
+}
 
-		return nil
-	})
+// RecordFallbackVisit records a visit that was sent to the fallback URL of an expired or deactivated link.
+// Fallback visits are kept apart from regular visits and don't change the link's visit count.
+func RecordFallbackVisit(db *gorm.DB, linkID uuid.UUID, details VisitDetails) error {
+	visit := &LinkVisit{
+		LinkID:    linkID,
+		VisitedAt: time.Now(),
+		Slug:      optionalString(details.Slug),
+		UserAgent: optionalString(details.UserAgent),
+		IPAddress: optionalString(details.IPAddress),
+		Referrer:  optionalString(details.Referrer),
+		Source:    optionalString(details.Source),
+		Type:      VisitTypeFallback,
+	}
+
+	return db.Create(visit).Error
 }
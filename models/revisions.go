@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SystemKeyID is the author of revisions the server makes on its own, such as deactivating expired links
+var SystemKeyID = uuid.Nil
+
+// linkRevisionChanged reports whether any of the revisioned fields differ between two link states
+func linkRevisionChanged(before, after Link) bool {
+	if before.RedirectTo != after.RedirectTo || before.IsActive != after.IsActive {
+		return true
+	}
+	if (before.ExpiresAt == nil) != (after.ExpiresAt == nil) {
+		return true
+	}
+	return before.ExpiresAt != nil && !before.ExpiresAt.Equal(*after.ExpiresAt)
+}
+
+// createLinkRevision stores a snapshot of the link's revisioned fields with the given revision number
+func createLinkRevision(db *gorm.DB, link Link, number int, createdBy uuid.UUID, createdAt time.Time) error {
+	revision := &LinkRevision{
+		LinkID:     link.ID,
+		Number:     number,
+		RedirectTo: link.RedirectTo,
+		ExpiresAt:  link.ExpiresAt,
+		IsActive:   link.IsActive,
+		CreatedBy:  createdBy,
+		CreatedAt:  createdAt,
+	}
+	return db.Create(revision).Error
+}
+
+// RecordInitialLinkRevision stores the first revision of a newly created link
+func RecordInitialLinkRevision(db *gorm.DB, link Link) error {
+	return createLinkRevision(db, link, 1, link.CreatedBy, time.Now())
+}
+
+// RecordLinkRevision stores a new revision if the destination, expiry or active flag changed between before and after.
+// Links created before revisions were tracked get a baseline revision of their previous state first,
+// so that the old values can still be rolled back to.
+func RecordLinkRevision(db *gorm.DB, before, after Link, changedBy uuid.UUID) error {
+	if !linkRevisionChanged(before, after) {
+		return nil
+	}
+
+	var latest int
+	if err := db.Model(&LinkRevision{}).Where("link_id = ?", after.ID).
+		Select("COALESCE(MAX(number), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	if latest == 0 {
+		if err := createLinkRevision(db, before, 1, before.CreatedBy, before.UpdatedAt); err != nil {
+			return err
+		}
+		latest = 1
+	}
+
+	return createLinkRevision(db, after, latest+1, changedBy, time.Now())
+}
+
+// RetrieveLinkRevisions returns every revision of a link, newest first
+func RetrieveLinkRevisions(db *gorm.DB, linkID uuid.UUID) ([]LinkRevision, error) {
+	var revisions []LinkRevision
+	if err := db.Where("link_id = ?", linkID).Order("number DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// RetrieveLinkRevision returns a single revision of a link by its number
+func RetrieveLinkRevision(db *gorm.DB, linkID uuid.UUID, number int) (*LinkRevision, error) {
+	var revision LinkRevision
+	if err := db.Where("link_id = ? AND number = ?", linkID, number).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
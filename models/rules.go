@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderLinkRules sorts preloaded link rules in evaluation order
+func OrderLinkRules(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// RetrieveLinkRules returns the rules of a link in evaluation order
+func RetrieveLinkRules(db *gorm.DB, linkID uuid.UUID) ([]LinkRule, error) {
+	var rules []LinkRule
+	if err := db.Where("link_id = ?", linkID).Order("position ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ReplaceLinkRules replaces all rules of a link, numbering the new rules in the given order
+func ReplaceLinkRules(db *gorm.DB, linkID uuid.UUID, rules []LinkRule) ([]LinkRule, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", linkID).Delete(&LinkRule{}).Error; err != nil {
+			return err
+		}
+
+		for i := range rules {
+			rules[i].LinkID = linkID
+			rules[i].Position = i + 1
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return tx.Create(&rules).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
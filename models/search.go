@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LinkSearchField is a field of links a search matches against
+type LinkSearchField string
+
+const (
+	LinkSearchShortened  LinkSearchField = "shortened"
+	LinkSearchRedirectTo LinkSearchField = "redirect_to"
+	LinkSearchTitle      LinkSearchField = "title"
+	LinkSearchNotes      LinkSearchField = "notes"
+	// tags matches the names of the link's tags
+	LinkSearchTags LinkSearchField = "tags"
+)
+
+// LinkSearchFields are the fields searched by default
+var LinkSearchFields = []LinkSearchField{LinkSearchShortened, LinkSearchRedirectTo, LinkSearchTitle, LinkSearchNotes, LinkSearchTags}
+
+// LinkSearch finds links containing a text in any of the searched fields, ignoring case
+type LinkSearch struct {
+	Query string
+	// Fields are the fields searched, empty searches every field
+	Fields []LinkSearchField
+	// SlugPrefix only matches shortened URLs starting with the query instead of containing it
+	SlugPrefix bool
+	// CreatedBy only searches the links created by a key, nil searches every link
+	CreatedBy *uuid.UUID
+}
+
+// searchLikeEscaper escapes the wildcards of a LIKE pattern with "!", which unlike a backslash is quoted the same on every backend
+var searchLikeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// caseInsensitiveLike returns a case insensitive LIKE condition on a column: ILIKE on Postgres, which the trigram indexes
+// of createIndexes speed up, and a portable LOWER() comparison on other backends
+func caseInsensitiveLike(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "postgres" {
+		return column + " ILIKE ? ESCAPE '!'"
+	}
+	return "LOWER(" + column + ") LIKE LOWER(?) ESCAPE '!'"
+}
+
+// Apply scopes a query on links to the search
+func (s LinkSearch) Apply(db *gorm.DB) *gorm.DB {
+	fields := s.Fields
+	if len(fields) == 0 {
+		fields = LinkSearchFields
+	}
+
+	contains := "%" + searchLikeEscaper.Replace(s.Query) + "%"
+	conditions := make([]string, 0, len(fields))
+	args := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case LinkSearchShortened:
+			pattern := contains
+			if s.SlugPrefix {
+				pattern = searchLikeEscaper.Replace(s.Query) + "%"
+			}
+			conditions = append(conditions, caseInsensitiveLike(db, "links.shortened"))
+			args = append(args, pattern)
+		case LinkSearchRedirectTo, LinkSearchTitle, LinkSearchNotes:
+			conditions = append(conditions, caseInsensitiveLike(db, "links."+string(field)))
+			args = append(args, contains)
+		case LinkSearchTags:
+			// tag names are stored in lowercase
+			conditions = append(conditions, "links.id IN (?)")
+			args = append(args, db.Session(&gorm.Session{NewDB: true}).Table("link_tags").Select("link_tags.link_id").
+				Joins("JOIN tags ON tags.id = link_tags.tag_id").Where("tags.name LIKE ? ESCAPE '!'", strings.ToLower(contains)))
+		}
+	}
+
+	db = db.Where(fmt.Sprintf("(%s)", strings.Join(conditions, " OR ")), args...)
+	if s.CreatedBy != nil {
+		db = db.Where("links.created_by = ?", *s.CreatedBy)
+	}
+	return db
+}
+
+// SearchLinks returns a page of the links matching the search and the filter
+func SearchLinks(db *gorm.DB, search LinkSearch, filter LinkFilter, params ListParams) ([]Link, PageInfo, error) {
+	query := db.Model(&Link{}).Scopes(filter.Apply, search.Apply)
+	return paginate(query, "links", params, linkSorts, "created_at", linkID, preloadLink)
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ServerSecretUnlockCookie signs unlock cookies when UNLOCK_COOKIE_SECRET isn't set
+const ServerSecretUnlockCookie = "unlock_cookie_secret"
+
+// RetrieveServerSecret returns the secret of the given name, generating a random one the first time.
+// Instances starting at the same time agree on the secret that was stored first.
+func RetrieveServerSecret(db *gorm.DB, name string) (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+
+	secret := ServerSecret{Name: name, Value: hex.EncodeToString(value)}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&secret).Error; err != nil {
+		return "", err
+	}
+	if err := db.Where("name = ?", name).First(&secret).Error; err != nil {
+		return "", err
+	}
+	return secret.Value, nil
+}
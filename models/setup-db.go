@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"time"
 
@@ -17,32 +18,213 @@ type SecretKey struct {
 	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;autoUpdateTime" json:"updated_at"`
 	IsActive  bool      `gorm:"not null;default:true" json:"is_active"`
 	IsAdmin   bool      `gorm:"not null;default:false" json:"is_admin"`
+	// FallbackURL is used by the key's expired or deactivated links that don't set their own fallback
+	FallbackURL *string `gorm:"type:varchar(2048)" json:"fallback_url"`
+	// DisplayName is shown publicly as the owner on the preview page of the key's links, the key name is never shown
+	DisplayName *string `gorm:"type:varchar(100)" json:"display_name"`
 }
 
-// Link represents the links table
+// Link represents the links table.
+// Deleting a link only sets DeletedAt, which moves it to the trash: it stops redirecting but keeps its slug and visits.
 type Link struct {
-	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	RedirectTo    string     `gorm:"type:varchar(2048);not null" json:"redirect_to"`
-	Shortened     string     `gorm:"type:varchar(100);unique;not null" json:"shortened"`
-	ExpiresAt     *time.Time `json:"expires_at"`
-	CreatedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP;autoUpdateTime" json:"updated_at"`
-	CreatedBy     uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
-	SecretKey     SecretKey  `gorm:"foreignKey:CreatedBy;references:ID" json:"secret_key"`
-	Visits        int        `gorm:"not null;default:0" json:"visits"`
-	LastVisitedAt *time.Time `json:"last_visited_at"`
-	IsActive      bool       `gorm:"not null;default:true" json:"is_active"`
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	RedirectTo    string         `gorm:"type:varchar(2048);not null" json:"redirect_to"`
+	Shortened     string         `gorm:"type:varchar(100);not null" json:"shortened"`
+	ExpiresAt     *time.Time     `json:"expires_at"`
+	CreatedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;autoUpdateTime" json:"updated_at"`
+	CreatedBy     uuid.UUID      `gorm:"type:uuid;not null" json:"created_by"`
+	SecretKey     SecretKey      `gorm:"foreignKey:CreatedBy;references:ID" json:"secret_key"`
+	Visits        int            `gorm:"not null;default:0" json:"visits"`
+	LastVisitedAt *time.Time     `json:"last_visited_at"`
+	IsActive      bool           `gorm:"not null;default:true" json:"is_active"`
+	RedirectType  int            `gorm:"not null;default:0" json:"redirect_type"`
+	PasswordHash  *string        `gorm:"type:varchar(100)" json:"-"`
+	MaxVisits     *int           `json:"max_visits"`
+	StartsAt      *time.Time     `json:"starts_at"`
+	PrelaunchURL  *string        `gorm:"type:varchar(2048)" json:"prelaunch_url"`
+	FallbackURL   *string        `gorm:"type:varchar(2048)" json:"fallback_url"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	Aliases       []LinkAlias    `gorm:"foreignKey:LinkID" json:"aliases"`
+	Rules         []LinkRule     `gorm:"foreignKey:LinkID" json:"rules"`
+	// StickyVariants keeps returning visitors on the same variant using a visitor cookie
+	StickyVariants bool          `gorm:"not null;default:false" json:"sticky_variants"`
+	Variants       []LinkVariant `gorm:"foreignKey:LinkID" json:"variants"`
+	// ForwardQuery decides how the query string of a visit is merged into the destination
+	ForwardQuery QueryForwardPolicy `gorm:"type:varchar(20);not null;default:'none'" json:"forward_query"`
+	// ForwardQueryKeys is the comma separated list of keys forwarded by the allowlist policy
+	ForwardQueryKeys *string `gorm:"type:varchar(1024)" json:"forward_query_keys"`
+	// PathMode decides what happens to path segments after the slug, e.g. "/jira/ABC-123"
+	PathMode LinkPathMode `gorm:"type:varchar(20);not null;default:'exact'" json:"path_mode"`
+	// DomainID is the short domain the slug lives on, nil for the default domain (PUBLIC_SITE_URL).
+	// Slugs are unique per domain, see createIndexes.
+	DomainID *uuid.UUID `gorm:"type:uuid;index" json:"domain_id"`
+	Domain   *Domain    `gorm:"foreignKey:DomainID" json:"domain,omitempty"`
+	// NamespaceID is the namespace the slug lives in, e.g. "eng" for "/eng/docs", nil for top-level slugs
+	NamespaceID *uuid.UUID `gorm:"type:uuid;index" json:"namespace_id"`
+	Namespace   *Namespace `gorm:"foreignKey:NamespaceID" json:"namespace,omitempty"`
+	// Tags are shared between links, so that links of e.g. one campaign can be listed and analyzed together
+	Tags []Tag `gorm:"many2many:link_tags" json:"tags"`
+	// Folder is an optional "/" separated path such as "marketing/2024", nil for links outside any folder
+	Folder *string `gorm:"type:varchar(255);index" json:"folder"`
+	// Title and Notes describe the link for its owners, they are never shown to visitors
+	Title *string `gorm:"type:varchar(255)" json:"title"`
+	Notes *string `gorm:"type:text" json:"notes"`
 }
 
+// LinkPathMode tells how a link handles path segments after its slug
+type LinkPathMode string
+
+const (
+	// only the bare slug resolves, extra path segments aren't found
+	LinkPathExact LinkPathMode = "exact"
+	// the segments fill the {1}, {2} or named placeholders of the destination
+	LinkPathTemplate LinkPathMode = "template"
+	// the segments are appended to the destination's path
+	LinkPathPassthrough LinkPathMode = "passthrough"
+)
+
+// QueryForwardPolicy tells which query parameters of a visit are forwarded to the link's destination
+type QueryForwardPolicy string
+
+const (
+	QueryForwardNone QueryForwardPolicy = "none"
+	// incoming parameters replace destination parameters with the same key
+	QueryForwardIncoming QueryForwardPolicy = "incoming"
+	// destination parameters are kept, incoming ones are only added if the destination doesn't have them
+	QueryForwardDestination QueryForwardPolicy = "destination"
+	// only the keys in ForwardQueryKeys are forwarded, replacing destination parameters with the same key
+	QueryForwardAllowlist QueryForwardPolicy = "allowlist"
+)
+
 // LinkVisit represents the link_visits table
 type LinkVisit struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
 	LinkID    uuid.UUID `gorm:"type:uuid;not null" json:"link_id"`
 	Link      Link      `gorm:"foreignKey:LinkID" json:"link"`
 	VisitedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"visited_at"`
+	Slug      *string   `gorm:"type:varchar(100)" json:"slug,omitempty"`
 	UserAgent *string   `gorm:"type:text" json:"user_agent,omitempty"`
 	IPAddress *string   `gorm:"type:inet" json:"ip_address,omitempty"`
 	Referrer  *string   `gorm:"type:text" json:"referrer,omitempty"`
+	Type      VisitType `gorm:"type:varchar(20);not null;default:'redirect'" json:"type"`
+	// Source is the attribution passed in the src query parameter, e.g. "qr" for QR code scans
+	Source *string `gorm:"type:varchar(50)" json:"source,omitempty"`
+	// RuleID is the rule that picked the destination of the visit, if any
+	RuleID *uuid.UUID `gorm:"type:uuid" json:"rule_id,omitempty"`
+	// VariantID is the A/B variant the visit was sent to, if any
+	VariantID *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"`
+}
+
+// VisitType tells whether a visit was redirected to the link's destination or to its fallback
+type VisitType string
+
+const (
+	VisitTypeRedirect VisitType = "redirect"
+	// fallback visits hit an expired or deactivated link and don't count towards its visits
+	VisitTypeFallback VisitType = "fallback"
+)
+
+// LinkRevision represents the link_revisions table.
+// Each revision is a snapshot of a link's destination, expiry and active flag after a change.
+type LinkRevision struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	LinkID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_link_revisions_link_number" json:"link_id"`
+	Number     int        `gorm:"not null;uniqueIndex:idx_link_revisions_link_number" json:"number"`
+	RedirectTo string     `gorm:"type:varchar(2048);not null" json:"redirect_to"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	IsActive   bool       `gorm:"not null" json:"is_active"`
+	CreatedBy  uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// HistoricalSlug represents the historical_slugs table.
+// When a link is renamed its old slug is kept here, so that shared copies of it keep redirecting and nobody else can claim it.
+type HistoricalSlug struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	LinkID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"link_id"`
+	DomainID          *uuid.UUID `gorm:"type:uuid" json:"domain_id"`
+	NamespaceID       *uuid.UUID `gorm:"type:uuid" json:"namespace_id"`
+	Slug              string     `gorm:"type:varchar(100);not null" json:"slug"`
+	RedirectToCurrent bool       `gorm:"not null;default:false" json:"redirect_to_current"`
+	CreatedAt         time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// LinkAlias represents the link_aliases table.
+// Aliases are extra slugs that resolve to the same link and share its analytics.
+type LinkAlias struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	LinkID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"link_id"`
+	DomainID    *uuid.UUID `gorm:"type:uuid" json:"domain_id"`
+	NamespaceID *uuid.UUID `gorm:"type:uuid" json:"namespace_id"`
+	Slug        string     `gorm:"type:varchar(100);not null" json:"slug"`
+	CreatedBy   uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt   time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// LinkRule represents the link_rules table.
+// Rules are evaluated by position, the first rule whose conditions all match picks the destination instead of RedirectTo.
+// Conditions that are left empty match every visit.
+type LinkRule struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	LinkID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"link_id"`
+	Position   int        `gorm:"not null" json:"position"`
+	Platform   *string    `gorm:"type:varchar(20)" json:"platform"`
+	Language   *string    `gorm:"type:varchar(35)" json:"language"`
+	Country    *string    `gorm:"type:varchar(2)" json:"country"`
+	ActiveFrom *time.Time `json:"active_from"`
+	ActiveTo   *time.Time `json:"active_to"`
+	RedirectTo string     `gorm:"type:varchar(2048);not null" json:"redirect_to"`
+	CreatedAt  time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// LinkVariant represents the link_variants table.
+// A link with variants splits its visits between their destinations in proportion to their weights.
+type LinkVariant struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	LinkID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_link_variants_link_name" json:"link_id"`
+	Name       string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_link_variants_link_name" json:"name"`
+	RedirectTo string    `gorm:"type:varchar(2048);not null" json:"redirect_to"`
+	Weight     int       `gorm:"not null" json:"weight"`
+	CreatedAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Domain represents the domains table.
+// Each domain is a branded short host served by this deployment, with its own slugs and settings.
+type Domain struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	// Host is the lowercase host name without a port, e.g. "go.example.com"
+	Host string `gorm:"type:varchar(255);unique;not null" json:"host"`
+	// DefaultRedirectType is used by the domain's links that don't set their own redirect_type, 0 uses DEFAULT_REDIRECT_CODE
+	DefaultRedirectType int `gorm:"not null;default:0" json:"default_redirect_type"`
+	// NotFoundURL is where visitors of unknown slugs on the domain are sent, nil shows the 404 page
+	NotFoundURL *string `gorm:"type:varchar(2048)" json:"not_found_url"`
+	// AllowedKeys may create links on the domain, if empty every key may. Admins always may.
+	AllowedKeys []SecretKey `gorm:"many2many:domain_allowed_keys" json:"allowed_keys"`
+	CreatedAt   time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP;autoUpdateTime" json:"updated_at"`
+}
+
+// Namespace represents the namespaces table.
+// A namespace is a reserved top-level slug such as "eng", links inside it are reached at "/eng/{slug}" on every domain.
+type Namespace struct {
+	ID   uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name string    `gorm:"type:varchar(100);unique;not null" json:"name"`
+	// OwnerID is the key that manages the namespace's members and every link inside it
+	OwnerID uuid.UUID `gorm:"type:uuid;not null;index" json:"owner_id"`
+	Owner   SecretKey `gorm:"foreignKey:OwnerID;references:ID" json:"owner"`
+	// Members may create links inside the namespace besides the owner
+	Members   []SecretKey `gorm:"many2many:namespace_members" json:"members"`
+	CreatedAt time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP;autoUpdateTime" json:"updated_at"`
+}
+
+// Tag represents the tags table.
+// Tags are created the first time a link uses them, their names are lowercase.
+type Tag struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name      string    `gorm:"type:varchar(50);unique;not null" json:"name"`
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 }
 
 // Request represents the requests table
@@ -65,11 +247,14 @@ const (
 type LogSource string
 
 const (
-	LogSourceDatabase LogSource = "database"
-	LogSourceAuth     LogSource = "auth"
-	LogSourceLinks    LogSource = "links"
-	LogSourceRequest  LogSource = "request"
-	LogSourceMisc     LogSource = "misc"
+	LogSourceDatabase   LogSource = "database"
+	LogSourceAuth       LogSource = "auth"
+	LogSourceLinks      LogSource = "links"
+	LogSourceRequest    LogSource = "request"
+	LogSourceAudit      LogSource = "audit"
+	LogSourceDomains    LogSource = "domains"
+	LogSourceNamespaces LogSource = "namespaces"
+	LogSourceMisc       LogSource = "misc"
 )
 
 // Log represents the logs table
@@ -81,6 +266,65 @@ type Log struct {
 	Message   string    `gorm:"type:text;not null" json:"message"`
 }
 
+// AuditAction represents the kind of mutation recorded in an audit event
+type AuditAction string
+
+const (
+	AuditActionKeyGenerate     AuditAction = "key.generate"
+	AuditActionKeyUpdate       AuditAction = "key.update"
+	AuditActionKeyDelete       AuditAction = "key.delete"
+	AuditActionLinkCreate      AuditAction = "link.create"
+	AuditActionLinkUpdate      AuditAction = "link.update"
+	AuditActionLinkDelete      AuditAction = "link.delete"
+	AuditActionLinkRestore     AuditAction = "link.restore"
+	AuditActionLinkPurge       AuditAction = "link.purge"
+	AuditActionLinkRollback    AuditAction = "link.rollback"
+	AuditActionSlugRelease     AuditAction = "link.release_slug"
+	AuditActionAliasAdd        AuditAction = "link.add_alias"
+	AuditActionAliasRemove     AuditAction = "link.remove_alias"
+	AuditActionLinkSetRules    AuditAction = "link.set_rules"
+	AuditActionVariantAdd      AuditAction = "link.add_variant"
+	AuditActionVariantUpdate   AuditAction = "link.update_variant"
+	AuditActionVariantRemove   AuditAction = "link.remove_variant"
+	AuditActionVariantReweight AuditAction = "link.reweight_variants"
+	AuditActionDomainCreate    AuditAction = "domain.create"
+	AuditActionDomainUpdate    AuditAction = "domain.update"
+	AuditActionDomainDelete    AuditAction = "domain.delete"
+	AuditActionNamespaceCreate AuditAction = "namespace.create"
+	AuditActionNamespaceUpdate AuditAction = "namespace.update"
+	AuditActionNamespaceDelete AuditAction = "namespace.delete"
+)
+
+// AuditTargetType represents the kind of record an audit event refers to
+type AuditTargetType string
+
+const (
+	AuditTargetKey       AuditTargetType = "key"
+	AuditTargetLink      AuditTargetType = "link"
+	AuditTargetDomain    AuditTargetType = "domain"
+	AuditTargetNamespace AuditTargetType = "namespace"
+)
+
+// AuditEvent represents the audit_events table
+type AuditEvent struct {
+	ID         uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ActorKeyID uuid.UUID       `gorm:"type:uuid;not null;index" json:"actor_key_id"`
+	Action     AuditAction     `gorm:"type:varchar(30);not null;index" json:"action"`
+	TargetType AuditTargetType `gorm:"type:varchar(20);not null" json:"target_type"`
+	TargetID   string          `gorm:"type:varchar(64);not null;index" json:"target_id"`
+	TargetName string          `gorm:"type:varchar(100)" json:"target_name"`
+	Timestamp  time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"timestamp"`
+	SourceIP   *string         `gorm:"type:inet" json:"source_ip,omitempty"`
+	Diff       string          `gorm:"type:jsonb;not null;default:'{}'" json:"diff"`
+}
+
+// ServerSecret represents the server_secrets table, which keeps secrets the server generates itself across restarts and instances
+type ServerSecret struct {
+	Name      string    `gorm:"type:varchar(50);primary_key" json:"name"`
+	Value     string    `gorm:"type:varchar(128);not null" json:"-"`
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
 // SetupDatabase initializes the database schema and indexes
 func SetupDatabase(db *gorm.DB) error {
 	// Enable UUID extension
@@ -89,10 +333,20 @@ func SetupDatabase(db *gorm.DB) error {
 	// Auto-migrate the schemas in the correct order
 	err := db.AutoMigrate(
 		&SecretKey{}, // Create the secret_keys table first
-		&Link{},      // Then create the links table
+		&Domain{},    // Links reference their domain
+		&Namespace{}, // and their namespace
+		&Tag{},
+		&Link{}, // Then create the links table
 		&LinkVisit{},
+		&LinkRevision{},
+		&HistoricalSlug{},
+		&LinkAlias{},
+		&LinkRule{},
+		&LinkVariant{},
 		&Request{},
 		&Log{}, // Create the logs table
+		&AuditEvent{},
+		&ServerSecret{},
 	)
 
 	if err != nil {
@@ -116,6 +370,31 @@ func createIndexes(db *gorm.DB) error {
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_links_shortened ON links(shortened) WHERE is_active = true")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_links_created_by ON links(created_by)")
 
+	// Slugs are unique per domain and namespace, links without a domain share the default domain
+	// and links without a namespace are top-level. These replace the global unique constraints of earlier versions.
+	for _, table := range []struct{ name, column string }{
+		{"links", "shortened"}, {"link_aliases", "slug"}, {"historical_slugs", "slug"},
+	} {
+		db.Exec(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS uni_%s_%s", table.name, table.name, table.column))
+		db.Exec(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s_%s_key", table.name, table.name, table.column))
+		db.Exec(fmt.Sprintf("DROP INDEX IF EXISTS idx_%s_domain_%s", table.name, table.column))
+		db.Exec(fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS idx_%s_scope_%s ON %s (COALESCE(domain_id, '%s'::uuid), COALESCE(namespace_id, '%s'::uuid), %s)",
+			table.name, table.column, table.name, uuid.Nil, uuid.Nil, table.column))
+	}
+
+	// Trigram indexes back the substring matches of link search. pg_trgm ships with Postgres,
+	// but enabling it may need more privileges than the app has, search then falls back to scanning the links.
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Printf("⚠️  pg_trgm is unavailable, link search won't be indexed: %v", err)
+	} else {
+		for _, index := range []struct{ table, column string }{
+			{"links", "shortened"}, {"links", "redirect_to"}, {"links", "title"}, {"links", "notes"}, {"tags", "name"},
+		} {
+			db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s_trgm ON %s USING gin (%s gin_trgm_ops)",
+				index.table, index.column, index.table, index.column))
+		}
+	}
+
 	// Secret keys index
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_secret_keys_key ON secret_keys(key) WHERE is_active = true")
 
@@ -127,5 +406,9 @@ func createIndexes(db *gorm.DB) error {
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_logs_type_timestamp ON logs(type, timestamp DESC)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_logs_source_timestamp ON logs(source, timestamp DESC)")
 
+	// Audit events indexes
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_events_actor_timestamp ON audit_events(actor_key_id, timestamp DESC)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_events_target_timestamp ON audit_events(target_id, timestamp DESC)")
+
 	return nil
 }
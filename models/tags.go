@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderTags sorts preloaded tags by name
+func OrderTags(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name ASC")
+}
+
+// FindOrCreateTags returns the tags with the given names, creating the ones that don't exist yet
+func FindOrCreateTags(db *gorm.DB, names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	newTags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		newTags = append(newTags, Tag{Name: name})
+	}
+	// concurrent requests may create the same tag, the unique name keeps one of them
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("name IN ?", names).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// TagNames returns the names of tags
+func TagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// ReplaceLinkTags sets the tags of a link
+func ReplaceLinkTags(db *gorm.DB, link *Link, tags []Tag) error {
+	if err := db.Model(link).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	link.Tags = tags
+	return nil
+}
+
+// TagStats aggregates the links with a tag and the visits they received
+type TagStats struct {
+	Tag    string `json:"tag"`
+	Links  int64  `json:"links"`
+	Visits int64  `json:"visits"`
+}
+
+// TagStatsFilter selects the tags, links and visits TagStats aggregate, empty fields don't filter
+type TagStatsFilter struct {
+	Tag       string
+	CreatedBy *uuid.UUID
+	Since     *time.Time
+	Until     *time.Time
+}
+
+// RetrieveTagStats counts the links and redirected visits of every tag, sorted by tag name.
+// Trashed links and fallback visits aren't counted.
+func RetrieveTagStats(db *gorm.DB, filter TagStatsFilter) ([]TagStats, error) {
+	visitsJoin := "LEFT JOIN link_visits ON link_visits.link_id = links.id AND link_visits.type = ?"
+	visitsArgs := []interface{}{VisitTypeRedirect}
+	if filter.Since != nil {
+		visitsJoin += " AND link_visits.visited_at >= ?"
+		visitsArgs = append(visitsArgs, *filter.Since)
+	}
+	if filter.Until != nil {
+		visitsJoin += " AND link_visits.visited_at < ?"
+		visitsArgs = append(visitsArgs, *filter.Until)
+	}
+
+	query := db.Table("tags").
+		Select("tags.name AS tag, COUNT(DISTINCT links.id) AS links, COUNT(link_visits.id) AS visits").
+		Joins("JOIN link_tags ON link_tags.tag_id = tags.id").
+		Joins("JOIN links ON links.id = link_tags.link_id AND links.deleted_at IS NULL").
+		Joins(visitsJoin, visitsArgs...)
+	if filter.Tag != "" {
+		query = query.Where("tags.name = ?", filter.Tag)
+	}
+	if filter.CreatedBy != nil {
+		query = query.Where("links.created_by = ?", *filter.CreatedBy)
+	}
+
+	var stats []TagStats
+	if err := query.Group("tags.name").Order("tags.name ASC").Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// VariantVisitCount is the number of visits a variant received
+type VariantVisitCount struct {
+	VariantID uuid.UUID
+	Visits    int64
+}
+
+// OrderLinkVariants sorts preloaded link variants by creation
+func OrderLinkVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at ASC")
+}
+
+// RetrieveLinkVariants returns the variants of a link in creation order
+func RetrieveLinkVariants(db *gorm.DB, linkID uuid.UUID) ([]LinkVariant, error) {
+	var variants []LinkVariant
+	if err := OrderLinkVariants(db).Where("link_id = ?", linkID).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// FindLinkVariant searches for a variant of a link by its name
+func FindLinkVariant(db *gorm.DB, linkID uuid.UUID, name string) (*LinkVariant, error) {
+	var variant LinkVariant
+	if err := db.Where("link_id = ? AND name = ?", linkID, name).First(&variant).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// ReweightLinkVariants sets the weights of several variants of a link at once
+func ReweightLinkVariants(db *gorm.DB, linkID uuid.UUID, weights map[string]int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, weight := range weights {
+			result := tx.Model(&LinkVariant{}).
+				Where("link_id = ? AND name = ?", linkID, name).
+				Update("weight", weight)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// CountVariantVisits returns the number of regular visits each variant of a link received
+func CountVariantVisits(db *gorm.DB, linkID uuid.UUID) ([]VariantVisitCount, error) {
+	var counts []VariantVisitCount
+	err := db.Model(&LinkVisit{}).
+		Select("variant_id, COUNT(*) AS visits").
+		Where("link_id = ? AND variant_id IS NOT NULL AND type = ?", linkID, VisitTypeRedirect).
+		Group("variant_id").
+		Scan(&counts).Error
+	return counts, err
+}
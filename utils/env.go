@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"go-link-shortener/lib"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +21,21 @@ type Env struct {
 	PUBLIC_SITE_URL string
 	ENABLE_DOCS     string
 	SERVER_PORT     string
+
+	// number of days a link stays in the trash before it is purged, 0 disables purging
+	TRASH_RETENTION_DAYS int
+	// status code used by links that don't set their own redirect type (301, 302, 307 or 308)
+	DEFAULT_REDIRECT_CODE int
+	// key used to sign the cookies that let a browser skip the password form of a protected link
+	UNLOCK_COOKIE_SECRET string
+	// URL visitors of expired or deactivated links are sent to when neither the link nor its key sets a fallback
+	DEFAULT_FALLBACK_URL string
+	// directory with HTML templates that replace the built-in error pages of the redirect router
+	PAGE_TEMPLATES_DIR string
+	// path to a local MaxMind country or city database, used by link rules that match on country
+	GEOIP_DB_PATH string
+	// maximum number of links a single bulk shorten request may create
+	BULK_SHORTEN_LIMIT int
 }
 
 func CheckTestEnvironment() bool {
@@ -34,6 +51,15 @@ func CheckLocalEnvironment() bool {
 // Make Global ENV available
 var ENV *Env
 
+// generatedUnlockCookieSecret is used when UNLOCK_COOKIE_SECRET isn't set
+var generatedUnlockCookieSecret string
+
+// SetGeneratedUnlockCookieSecret sets the secret that signs unlock cookies when UNLOCK_COOKIE_SECRET isn't set.
+// It is generated once and stored in the database, so that it isn't derived from any other secret.
+func SetGeneratedUnlockCookieSecret(secret string) {
+	generatedUnlockCookieSecret = secret
+}
+
 func LoadEnv() *Env {
 	isTestMode := CheckTestEnvironment()
 	isLocalMode := CheckLocalEnvironment()
@@ -65,6 +91,26 @@ func LoadEnv() *Env {
 		serverPort = "8080"
 	}
 
+	trashRetentionDays := getEnvInt("TRASH_RETENTION_DAYS", 30)
+
+	defaultRedirectCode := getEnvInt("DEFAULT_REDIRECT_CODE", lib.DEFAULT_REDIRECT_STATUS_CODE)
+	if !lib.REDIRECT_STATUS_CODES[defaultRedirectCode] {
+		log.Printf("🛈  Invalid value for DEFAULT_REDIRECT_CODE: %d, using default: %d", defaultRedirectCode, lib.DEFAULT_REDIRECT_STATUS_CODE)
+		defaultRedirectCode = lib.DEFAULT_REDIRECT_STATUS_CODE
+	}
+
+	bulkShortenLimit := getEnvInt("BULK_SHORTEN_LIMIT", lib.DEFAULT_BULK_SHORTEN_LIMIT)
+	if bulkShortenLimit == 0 {
+		log.Printf("🛈  Invalid value for BULK_SHORTEN_LIMIT: 0, using default: %d", lib.DEFAULT_BULK_SHORTEN_LIMIT)
+		bulkShortenLimit = lib.DEFAULT_BULK_SHORTEN_LIMIT
+	}
+
+	unlockCookieSecret := os.Getenv("UNLOCK_COOKIE_SECRET")
+	if unlockCookieSecret == "" {
+		// fall back to the random secret stored in the database, see SetGeneratedUnlockCookieSecret
+		unlockCookieSecret = generatedUnlockCookieSecret
+	}
+
 	env := Env{
 		DBHost:          os.Getenv("DB_HOST"),
 		DBUser:          os.Getenv("DB_USER"),
@@ -77,6 +123,14 @@ func LoadEnv() *Env {
 		PUBLIC_SITE_URL: os.Getenv("PUBLIC_SITE_URL"),
 		ENABLE_DOCS:     os.Getenv("ENABLE_DOCS"),
 		SERVER_PORT:     os.Getenv("SERVER_PORT"),
+
+		TRASH_RETENTION_DAYS:  trashRetentionDays,
+		DEFAULT_REDIRECT_CODE: defaultRedirectCode,
+		UNLOCK_COOKIE_SECRET:  unlockCookieSecret,
+		DEFAULT_FALLBACK_URL:  os.Getenv("DEFAULT_FALLBACK_URL"),
+		PAGE_TEMPLATES_DIR:    os.Getenv("PAGE_TEMPLATES_DIR"),
+		GEOIP_DB_PATH:         os.Getenv("GEOIP_DB_PATH"),
+		BULK_SHORTEN_LIMIT:    bulkShortenLimit,
 	}
 
 	// verify that all required environment variables are set
@@ -90,3 +144,19 @@ func LoadEnv() *Env {
 	ENV = &env
 	return &env
 }
+
+// getEnvInt reads a non-negative integer environment variable, falling back to the default if it's unset or invalid
+func getEnvInt(name string, defaultValue int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		log.Printf("🛈  Invalid value for %s: '%s', using default: %d", name, raw, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
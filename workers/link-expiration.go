@@ -2,13 +2,13 @@ package workers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"go-link-shortener/models"
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // LinkExpirationWorker handles the scheduled expiration of links in the system
@@ -45,49 +45,62 @@ func (w *LinkExpirationWorker) Start(ctx context.Context) error {
 	}
 }
 
+// expiredLinksCondition matches active links that should be deactivated
+const expiredLinksCondition = "is_active = ? AND ((expires_at IS NOT NULL AND expires_at < ?) OR (max_visits IS NOT NULL AND visits >= max_visits))"
+
 // processExpiredLinks handles the deactivation of expired links
 // Links are considered expired if:
 // - Their explicit expiration date has passed
+// - They have been visited max_visits times
+// Expired links keep their slug, so that its visitors are sent to the fallback or shown the expired page.
+// Every deactivation is recorded as a revision of the link, authored by models.SystemKeyID.
 // Returns an error if database operations fail
 func (w *LinkExpirationWorker) processExpiredLinks() error {
-	prefix := make([]byte, 12)
-	if _, err := rand.Read(prefix); err != nil {
-		return err
-	}
-	randomPrefix := "expired_" + base64.URLEncoding.EncodeToString(prefix)[:12] + "_"
-
-	var affectedRows int64
-	var results []struct {
-		ID string
-	}
+	var expired []models.Link
 
+	now := time.Now()
 	err := w.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&models.Link{}).
-			Where("is_active = ? AND expires_at IS NOT NULL AND expires_at < ?", true, time.Now()).
-			Count(&affectedRows).Error; err != nil {
+		// the links are locked, so that an owner can't change them between the update and their revisions
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(expiredLinksCondition, true, now).
+			Find(&expired).Error; err != nil {
 			return err
 		}
 
-		if affectedRows == 0 {
+		if len(expired) == 0 {
 			return nil
 		}
 
-		return tx.Model(&models.Link{}).
-			Where("is_active = ? AND expires_at IS NOT NULL AND expires_at < ?", true, time.Now()).
+		ids := make([]uuid.UUID, len(expired))
+		for i, link := range expired {
+			ids[i] = link.ID
+		}
+
+		if err := tx.Model(&models.Link{}).
+			Where("id IN ?", ids).
 			Updates(map[string]interface{}{
 				"is_active":  false,
-				"shortened":  gorm.Expr("? || id::text", randomPrefix),
-				"updated_at": time.Now(),
-			}).
-			Scan(&results).Error
+				"updated_at": now,
+			}).Error; err != nil {
+			return err
+		}
+
+		for _, link := range expired {
+			deactivated := link
+			deactivated.IsActive = false
+			if err := models.RecordLinkRevision(tx, link, deactivated, models.SystemKeyID); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 
 	if err != nil {
 		return err
 	}
 
-	if affectedRows > 0 {
-		log.Printf("Processed %d expired links", affectedRows)
+	if len(expired) > 0 {
+		log.Printf("Processed %d expired links", len(expired))
 	}
 
 	return nil
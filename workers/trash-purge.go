@@ -0,0 +1,61 @@
+package workers
+
+import (
+	"context"
+	"go-link-shortener/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrashPurgeWorker permanently deletes links that have been in the trash for longer than the retention period
+type TrashPurgeWorker struct {
+	db        *gorm.DB
+	interval  time.Duration
+	retention time.Duration
+}
+
+// NewTrashPurgeWorker creates a new worker instance with the provided database connection
+// Trashed links are purged after retentionDays days, and the worker runs every hour
+func NewTrashPurgeWorker(db *gorm.DB, retentionDays int) *TrashPurgeWorker {
+	return &TrashPurgeWorker{
+		db:        db,
+		interval:  time.Hour,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Start begins the worker process to purge old trashed links
+// It runs continuously until the provided context is cancelled
+// Returns an error if the context is cancelled
+func (w *TrashPurgeWorker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := w.purgeTrashedLinks(); err != nil {
+				log.Printf("Error purging trashed links: %v", err)
+			}
+		}
+	}
+}
+
+// purgeTrashedLinks permanently deletes links (and their visits) trashed before the retention cutoff
+// Returns an error if database operations fail
+func (w *TrashPurgeWorker) purgeTrashedLinks() error {
+	purged, err := models.PurgeTrashedLinksBefore(w.db, time.Now().Add(-w.retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d trashed links", purged)
+	}
+
+	return nil
+}